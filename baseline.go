@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/felipecruz91/skout/scan"
+)
+
+// Baseline is the set of known vulnerabilities written with --update-baseline, which are not reported by the
+// following analyses run with --baseline so that only the new ones are reported and fail the thresholds.
+type Baseline struct {
+	// Images holds the sorted IDs of the known vulnerabilities of every image repository, e.g. docker.io/library/nginx,
+	// so that they are still known once the image is updated
+	Images map[string][]string `json:"images"`
+}
+
+// readBaseline reads the baseline of the given file, or returns an empty baseline if the file doesn't exist yet.
+func readBaseline(filename string) (Baseline, error) {
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return Baseline{Images: map[string][]string{}}, nil
+	}
+	if err != nil {
+		return Baseline{}, err
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(b, &baseline); err != nil {
+		return Baseline{}, fmt.Errorf("parsing baseline %s: %w", filename, err)
+	}
+	return baseline, nil
+}
+
+// newBaseline returns the baseline of the vulnerabilities of the given SARIF reports, keyed by image name.
+func newBaseline(reports map[string]scan.SarifReport) Baseline {
+	baseline := Baseline{Images: make(map[string][]string)}
+	for image, report := range reports {
+		// several images of the same repository, e.g. two tags, share the baseline
+		repository := scan.Repository(image)
+		ids := append(baseline.Images[repository], report.RuleIDs()...)
+		slices.Sort(ids)
+		baseline.Images[repository] = slices.Compact(ids)
+	}
+	return baseline
+}
+
+// writeBaseline writes the given baseline into the given file as indented JSON, creating its parent directories if needed.
+func writeBaseline(filename string, baseline Baseline) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0o644)
+}
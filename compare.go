@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/felipecruz91/skout/scan"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Comparison is the change in the vulnerabilities found between a previous report and the current one.
+type Comparison struct {
+	// Images holds the images whose vulnerabilities changed, sorted by image name
+	Images   []ImageComparison    `json:"images"`
+	Previous scan.Vulnerabilities `json:"previous"`
+	Current  scan.Vulnerabilities `json:"current"`
+}
+
+// ImageComparison is the change in the vulnerabilities found in an image between two reports.
+type ImageComparison struct {
+	Image    string               `json:"image"`
+	Previous scan.Vulnerabilities `json:"previous"`
+	Current  scan.Vulnerabilities `json:"current"`
+	// NewCVEs and ResolvedCVEs are only known if both reports include the details of every image
+	NewCVEs      []string `json:"newCves,omitempty"`
+	ResolvedCVEs []string `json:"resolvedCves,omitempty"`
+}
+
+// Degraded returns whether the number of vulnerabilities of any severity increased.
+func (c Comparison) Degraded() bool {
+	return c.Current.Critical > c.Previous.Critical || c.Current.High > c.Previous.High ||
+		c.Current.Medium > c.Previous.Medium || c.Current.Low > c.Previous.Low
+}
+
+// imageSummary is the vulnerabilities found in an image of a report.
+type imageSummary struct {
+	vulns scan.Vulnerabilities
+	// cves is nil if the report doesn't include the details of the image
+	cves map[string]bool
+}
+
+// readReportFile reads a report previously written in the JSON format.
+func readReportFile(filename string) (Report, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return Report{}, err
+	}
+
+	var report Report
+	if err := json.Unmarshal(b, &report); err != nil {
+		return Report{}, fmt.Errorf("parsing JSON report %s: %w", filename, err)
+	}
+
+	return report, nil
+}
+
+// summarizeImages returns the vulnerabilities of every image successfully analyzed in the report, keyed by the
+// image name of the containers rather than by digest, so that an image updated between two reports is compared.
+func summarizeImages(report Report) map[string]imageSummary {
+	summaries := make(map[string]imageSummary)
+	for _, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			if _, ok := summaries[container.Image]; ok || container.Error != "" {
+				continue
+			}
+
+			summary := imageSummary{vulns: container.Vulnerabilities}
+			if report.Details != nil {
+				summary.cves = make(map[string]bool)
+				for ref, findings := range report.Details {
+					// the details are keyed by the normalized image, pinned to its digest when known
+					if ref != container.ScanRef() && (container.Digest == "" || !strings.HasSuffix(ref, "@"+container.Digest)) {
+						continue
+					}
+					for _, f := range findings {
+						summary.cves[f.CVE] = true
+					}
+				}
+			}
+			summaries[container.Image] = summary
+		}
+	}
+	return summaries
+}
+
+// compareReports returns the change in the vulnerabilities of every image between the previous and the current report.
+func compareReports(previous, current Report) Comparison {
+	prev, cur := summarizeImages(previous), summarizeImages(current)
+
+	var images []string
+	for image := range prev {
+		images = append(images, image)
+	}
+	for image := range cur {
+		if _, ok := prev[image]; !ok {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+
+	comparison := Comparison{Images: []ImageComparison{}, Previous: previous.Total, Current: current.Total}
+	for _, image := range images {
+		p, c := prev[image], cur[image]
+		ic := ImageComparison{Image: image, Previous: p.vulns, Current: c.vulns}
+		if p.cves != nil && c.cves != nil {
+			ic.NewCVEs = difference(c.cves, p.cves)
+			ic.ResolvedCVEs = difference(p.cves, c.cves)
+		}
+		if ic.Previous != ic.Current || len(ic.NewCVEs) > 0 || len(ic.ResolvedCVEs) > 0 {
+			comparison.Images = append(comparison.Images, ic)
+		}
+	}
+
+	return comparison
+}
+
+// difference returns the sorted CVEs of a that are not in b.
+func difference(a, b map[string]bool) []string {
+	var cves []string
+	for cve := range a {
+		if !b[cve] {
+			cves = append(cves, cve)
+		}
+	}
+	sort.Strings(cves)
+	return cves
+}
+
+// writeComparisonTable renders into w a table with the change in the vulnerabilities of every image.
+func writeComparisonTable(w io.Writer, comparison Comparison) error {
+	if len(comparison.Images) == 0 {
+		_, err := fmt.Fprintln(w, "No changes since the previous report.")
+		return err
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Image", "Critical", "High", "Medium", "Low", "New CVEs", "Resolved CVEs"})
+	for _, ic := range comparison.Images {
+		t.AppendRow(table.Row{
+			ic.Image,
+			fmtChange(ic.Previous.Critical, ic.Current.Critical),
+			fmtChange(ic.Previous.High, ic.Current.High),
+			fmtChange(ic.Previous.Medium, ic.Current.Medium),
+			fmtChange(ic.Previous.Low, ic.Current.Low),
+			strings.Join(ic.NewCVEs, "\n"),
+			strings.Join(ic.ResolvedCVEs, "\n"),
+		})
+	}
+	t.AppendFooter(table.Row{
+		"Total",
+		fmtChange(comparison.Previous.Critical, comparison.Current.Critical),
+		fmtChange(comparison.Previous.High, comparison.Current.High),
+		fmtChange(comparison.Previous.Medium, comparison.Current.Medium),
+		fmtChange(comparison.Previous.Low, comparison.Current.Low),
+	})
+	t.SetStyle(table.StyleLight)
+	t.Style().Options.SeparateRows = true
+
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
+// fmtChange formats the current count along with its change from the previous one, e.g. "5 (+2)".
+func fmtChange(previous, current int) string {
+	if previous == current {
+		return fmt.Sprintf("%d", current)
+	}
+	return fmt.Sprintf("%d (%+d)", current, current-previous)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the configuration file read from the working directory when --config is not set.
+const defaultConfigFile = ".skout.yaml"
+
+// imagesFlags set the images to analyze without connecting to a cluster.
+var imagesFlags = []string{"images", "images-file"}
+
+// manifestFlags set the Kubernetes manifests whose images are analyzed without connecting to a cluster.
+var manifestFlags = []string{"manifest", "from-stdin"}
+
+// clusterFlags select the pods of the cluster whose images are analyzed.
+var clusterFlags = []string{"kubeconfig", "context", "in-cluster", "namespace", "all-namespaces", "exclude-namespace", "exclude-system-namespaces", "selector", "pod", "node", "workloads", "include-all-phases"}
+
+// exclusiveFlags are the groups of flags that cannot be combined with each other.
+var exclusiveFlags = [][]string{
+	{"namespace", "all-namespaces", "exclude-namespace"},
+	{"namespace", "all-namespaces", "exclude-system-namespaces"},
+	{"verbose", "quiet", "log-level"},
+	{"in-cluster", "kubeconfig"},
+	{"in-cluster", "context"},
+	{"pod", "all-namespaces"},
+	{"pod", "selector"},
+	{"pod", "node"},
+	{"pod", "workloads"},
+	{"pod", "include-all-phases"},
+	{"node", "workloads"},
+	{"watch", "dry-run"},
+	{"wide", "columns"},
+}
+
+// overridden returns whether the value of the given flag in the config file is overridden by a flag set on the
+// command line that can't be combined with it, e.g. "namespace" by --all-namespaces, or "namespace" by --images as
+// the images, the manifests and the cluster are alternative sources of the images to analyze.
+func overridden(name string, cli map[string]bool) bool {
+	for _, group := range exclusiveFlags {
+		if slices.Contains(group, name) && slices.ContainsFunc(group, func(other string) bool { return other != name && cli[other] }) {
+			return true
+		}
+	}
+
+	sources := [][]string{imagesFlags, manifestFlags, clusterFlags}
+	for i, source := range sources {
+		if !slices.Contains(source, name) {
+			continue
+		}
+		for j, other := range sources {
+			if i != j && slices.ContainsFunc(other, func(other string) bool { return cli[other] }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyConfigFile sets the flags of fs from the given YAML configuration file, whose keys are the long names of
+// the flags, e.g. "namespace: default". The flags set on the command line, given by cli, take precedence over the
+// file: their values in the file are ignored, as are the values of the flags they can't be combined with. If
+// filename is empty, the default configuration file is read if it exists.
+func applyConfigFile(fs *pflag.FlagSet, filename string, cli map[string]bool) error {
+	if filename == "" {
+		if _, err := os.Stat(defaultConfigFile); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		filename = defaultConfigFile
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(b, &values); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", filename, err)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := fs.Lookup(name)
+		if flag == nil || flag.Name == "config" {
+			return fmt.Errorf("unknown flag %q in config file %s", name, filename)
+		}
+		// the key can be an alias of the flag, e.g. "output" for --report-format, so the flag is matched by its name
+		if cli[flag.Name] || overridden(flag.Name, cli) || values[name] == nil {
+			continue
+		}
+
+		// lists set the flag once per element, as repeating the flag on the command line does
+		elements, ok := values[name].([]any)
+		if !ok {
+			elements = []any{values[name]}
+		}
+		for _, element := range elements {
+			if err := fs.Set(name, fmt.Sprint(element)); err != nil {
+				return fmt.Errorf("invalid value of %q in config file %s: %w", name, filename, err)
+			}
+		}
+	}
+
+	return nil
+}
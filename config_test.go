@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		args   []string
+		check  func(opts options) bool
+	}{
+		{
+			name:   "file value is applied",
+			config: "namespace: default\nconcurrency: 8\n",
+			check:  func(opts options) bool { return opts.namespace == "default" && opts.concurrency == 8 },
+		},
+		{
+			name:   "command line overrides the file",
+			config: "namespace: default\n",
+			args:   []string{"--namespace", "payments"},
+			check:  func(opts options) bool { return opts.namespace == "payments" },
+		},
+		{
+			name:   "command line overrides an alias key of the file",
+			config: "output: bogus\n",
+			args:   []string{"-o", "json"},
+			check:  func(opts options) bool { return opts.reportFormat == reportFormatJSON },
+		},
+		{
+			name:   "command line flag overrides an alias key of the file",
+			config: "max-age: 1h\n",
+			args:   []string{"--cache-ttl", "2h"},
+			check:  func(opts options) bool { return opts.cacheTTL == 2*time.Hour },
+		},
+		{
+			name:   "alias key of the file is applied",
+			config: "max-age: 1h\n",
+			check:  func(opts options) bool { return opts.cacheTTL == time.Hour },
+		},
+		{
+			name:   "conflicting file value is dropped",
+			config: "namespace: default\n",
+			args:   []string{"-A"},
+			check:  func(opts options) bool { return opts.allNamespaces && opts.namespace == "" },
+		},
+		{
+			name:   "cluster file value is dropped with --images",
+			config: "namespace: default\n",
+			args:   []string{"--images", "nginx"},
+			check:  func(opts options) bool { return opts.namespace == "" && len(opts.images) == 1 },
+		},
+		{
+			name:   "log level file value is dropped with --quiet",
+			config: "verbose: true\n",
+			args:   []string{"-q"},
+			check:  func(opts options) bool { return opts.quiet && !opts.verbose },
+		},
+		{
+			name:   "file interval without --watch is ignored",
+			config: "interval: 5m\n",
+			check:  func(opts options) bool { return !opts.watch },
+		},
+		{
+			name:   "file list sets the flag once per element",
+			config: "ignore-cve:\n  - CVE-2023-1\n  - CVE-2023-2\n",
+			check:  func(opts options) bool { return len(opts.ignoreCVEs) == 2 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "skout.yaml")
+			if err := os.WriteFile(filename, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			args := append([]string{"--config", filename}, tt.args...)
+			opts, err := parseFlags(args, io.Discard, io.Discard)
+			if err != nil {
+				t.Fatalf("parseFlags(%q) with config %q returned error: %v", tt.args, tt.config, err)
+			}
+			if !tt.check(opts) {
+				t.Errorf("parseFlags(%q) with config %q returned unexpected options: %+v", tt.args, tt.config, opts)
+			}
+		})
+	}
+}
+
+func TestApplyConfigFileUnknownKey(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "skout.yaml")
+	if err := os.WriteFile(filename, []byte("bogus: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseFlags([]string{"--config", filename}, io.Discard, io.Discard); err == nil {
+		t.Error("parseFlags with an unknown config key returned no error")
+	}
+}
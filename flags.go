@@ -0,0 +1,704 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/felipecruz91/skout/scan"
+	"github.com/spf13/pflag"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// defaultScoutCommand is the docker scout command whose SARIF report holds the vulnerabilities of an image.
+const defaultScoutCommand = "cves"
+
+// usageHeader is the beginning of the help of skout, printed before the description of the flags.
+const usageHeader = `skout analyzes with docker scout the images of the containers running in a Kubernetes cluster
+and reports their vulnerabilities.
+
+Usage:
+  skout [flags] [docker scout cves flags] [-- docker scout cves flags]
+
+Examples:
+  # Analyze the pods of all the namespaces of the current kubeconfig context
+  skout
+
+  # Analyze the pods of the default namespace matching a label selector and fail on critical vulnerabilities
+  skout --namespace default -l app=web --fail-on critical
+
+  # Forward the --only-fixed flag to docker scout and write the report as JSON
+  skout -o json --report-file report.json -- --only-fixed
+
+Flags:
+`
+
+// usageFooter is the end of the help of skout, printed after the description of the flags.
+const usageFooter = `
+Docker scout flags:
+  Any flag not listed above, and every argument after a "--" separator, is forwarded as is to "docker scout cves",
+  see "docker scout cves --help". The --format flag is set by skout and ignored, and --output (-o) is the format
+  of the report of skout.
+`
+
+// systemNamespaces are the namespaces of the cluster components skipped with --exclude-system-namespaces.
+var systemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// internalScoutFlags are the docker scout flags that skout sets itself to generate the SARIF reports,
+// so they are never forwarded to docker scout. The --output and -o flags of docker scout are the format of
+// the report of skout instead, but --o is still ignored.
+var internalScoutFlags = []string{"format", "o"}
+
+// options holds the command line options of skout.
+type options struct {
+	kubeConfig       string
+	kubeContext      string
+	inCluster        bool
+	namespace        string
+	allNamespaces    bool
+	selector         string
+	pod              string
+	node             string
+	workloads        bool
+	includeAllPhases bool
+	dryRun           bool
+	watch            bool
+	interval         time.Duration
+	verbose          bool
+	quiet            bool
+	logLevel         string
+	logFormat        string
+	version          bool
+	concurrency      int
+	retries          int
+	retryDelay       time.Duration
+	timeout          time.Duration
+	resultsDir       string
+	keepSarif        bool
+	scoutImage       string
+	scoutCommand     string
+	scoutEnv         []string
+	cacheDir         string
+	cacheTTL         time.Duration
+	noCache          bool
+	countOccurrences bool
+	ignoreCVEs       []string
+	baseline         string
+	updateBaseline   bool
+	reportFormat     string
+	reportFile       string
+	metricsFile      string
+	slackWebhook     string
+	upload           string
+	mergeSarif       bool
+	sbom             bool
+	recommendations  bool
+	details          bool
+	stream           bool
+	compare          string
+	groupBy          string
+	rank             bool
+	summary          bool
+	columns          []string
+	wide             bool
+	noColor          bool
+	severity         string
+	pruneSarif       bool
+	exitCode         bool
+	failOnError      bool
+	failOn           string
+	failOnFixable    string
+	maxCritical      int
+	maxHigh          int
+	maxMedium        int
+	maxLow           int
+	maxTotal         int
+	maxScore         int
+	weights          string
+	// thresholds are computed from exitCode, failOn, the max* options and weights
+	thresholds Thresholds
+	// showScore is whether the risk score is shown in the tables, when --weights or --max-score is set
+	showScore bool
+	// fixableThresholds are computed from failOnFixable
+	fixableThresholds Thresholds
+	// images are the images set with --images and --images-file, analyzed without connecting to a cluster
+	images []string
+	// excludeNamespaces are the namespaces skipped when analyzing all the namespaces, including the system ones with
+	// --exclude-system-namespaces
+	excludeNamespaces []string
+	// manifests are the files of Kubernetes manifests set with --manifest, "-" being the standard input, whose
+	// images are analyzed without connecting to a cluster
+	manifests []string
+	// registryAuths are the registries credentials set with --registry-auth
+	registryAuths []registryAuth
+	// registryMirrors are the mirrors set with --registry-mirror, keyed by registry
+	registryMirrors map[string]string
+	// scoutArgs are the arguments not known by skout, which are forwarded to docker scout
+	scoutArgs []string
+	// ignoredArgs are the docker scout flags ignored as they are used internally by skout
+	ignoredArgs []string
+	// level is the slog level computed from logLevel, verbose and quiet
+	level slog.Level
+	// template is the template parsed from --template-file, used with --report-format template
+	template *template.Template
+}
+
+// parseFlags parses the command line arguments, without the program name, into options.
+// It returns pflag.ErrHelp if the usage was requested, after writing it to stdout.
+func parseFlags(args []string, stdout, stderr io.Writer) (options, error) {
+	var (
+		opts          options
+		registryAuths []string
+		mirrors       []string
+		images        []string
+		imagesFile    string
+		ignoreFile    string
+		configFile    string
+		excludeSystem bool
+		fromStdin     bool
+		templateFile  string
+		help          bool
+	)
+
+	fs := pflag.NewFlagSet("skout", pflag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVarP(&help, "help", "h", false, "print this help and exit")
+	fs.StringVar(&configFile, "config", "", fmt.Sprintf("YAML file with the default value of the flags, keyed by flag name (default %s if it exists)", defaultConfigFile))
+	fs.StringVar(&opts.kubeConfig, "kubeconfig", "", "path to the kubeconfig file (default the files of KUBECONFIG merged together, or ~/.kube/config)")
+	fs.StringVar(&opts.kubeContext, "context", "", "name of the kubeconfig context to use (default current context)")
+	fs.BoolVar(&opts.inCluster, "in-cluster", false, "use the in-cluster configuration of the pod service account")
+	fs.StringVar(&opts.namespace, "namespace", "", "namespace of the pods to analyze (default all namespaces)")
+	fs.BoolVarP(&opts.allNamespaces, "all-namespaces", "A", false, "analyze the pods of all namespaces")
+	fs.StringSliceVar(&opts.excludeNamespaces, "exclude-namespace", nil, "namespace skipped when analyzing all the namespaces, can be repeated")
+	fs.BoolVar(&excludeSystem, "exclude-system-namespaces", false, fmt.Sprintf("skip the namespaces of the cluster components when analyzing all the namespaces: %s", strings.Join(systemNamespaces, ", ")))
+	fs.StringVarP(&opts.selector, "selector", "l", "", "label selector to filter the pods to analyze, supports '=', '==', '!=', 'in' and 'notin' (e.g. -l key1=value1,key2=value2)")
+	fs.StringVar(&opts.pod, "pod", "", "name of the only pod to analyze, in the namespace set by --namespace")
+	fs.StringVar(&opts.node, "node", "", "only analyze the pods scheduled on the given node")
+	fs.BoolVar(&opts.workloads, "workloads", false, "analyze the pod templates of Deployments, StatefulSets, DaemonSets, CronJobs and Jobs instead of the running pods")
+	fs.StringSliceVar(&images, "images", nil, "comma-separated list of images to analyze instead of the images running in the cluster, can be repeated")
+	fs.StringVar(&imagesFile, "images-file", "", "file with an image to analyze per line instead of the images running in the cluster")
+	fs.StringArrayVar(&opts.manifests, "manifest", nil, "file of Kubernetes manifests whose images are analyzed instead of the images running in the cluster, e.g. the output of helm template, - for stdin, can be repeated")
+	fs.BoolVar(&fromStdin, "from-stdin", false, "analyze the images of the pods read from stdin instead of the images running in the cluster, e.g. the output of kubectl get pods -o json, same as --manifest -")
+	fs.BoolVar(&opts.includeAllPhases, "include-all-phases", false, "analyze the pods in any phase, including completed, failed and evicted pods, instead of only the running ones")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "list the images that would be analyzed, and the containers referencing them, without analyzing them")
+	fs.BoolVar(&opts.watch, "watch", false, "analyze the images again every --interval, rendering the report again every time, until interrupted")
+	fs.DurationVar(&opts.interval, "interval", defaultInterval, "delay between two analyses with --watch")
+	fs.BoolVarP(&opts.verbose, "verbose", "v", false, "enable verbose logging, same as --log-level debug")
+	fs.BoolVarP(&opts.quiet, "quiet", "q", false, "only log errors, same as --log-level error")
+	fs.StringVar(&opts.logLevel, "log-level", "info", fmt.Sprintf("minimum level of the logs, one of: %s", strings.Join(logLevels, ", ")))
+	fs.StringVar(&opts.logFormat, "log-format", logFormatText, fmt.Sprintf("format of the logs, one of: %s", strings.Join(logFormats, ", ")))
+	fs.StringArrayVar(&registryAuths, "registry-auth", nil, "credentials of a private registry as REGISTRY=USERNAME:PASSWORD, can be repeated (only used with the docker/scout-cli image)")
+	fs.BoolVar(&opts.version, "version", false, "print the version of skout and exit")
+	fs.StringVar(&opts.scoutCommand, "scout-command", defaultScoutCommand, "docker scout command run on every image, commands other than cves only display their raw output, e.g. quickview")
+	fs.StringArrayVar(&mirrors, "registry-mirror", nil, "mirror the images of a registry are pulled from as REGISTRY=MIRROR, e.g. docker.io=mirror.example.com/dockerhub, also used for the default --scout-image, can be repeated")
+	fs.StringArrayVar(&opts.scoutEnv, "scout-env", nil, "environment variable of docker scout as KEY=VALUE, e.g. a DOCKER_SCOUT_ setting, can be repeated (the proxy variables are always forwarded to the docker/scout-cli image)")
+	fs.StringVar(&opts.scoutImage, "scout-image", scan.DefaultImage, "docker/scout-cli image run when the docker scout CLI plugin is not installed, e.g. to pin its version or use a mirror")
+	fs.IntVar(&opts.concurrency, "concurrency", defaultConcurrency, "maximum number of images analyzed in parallel")
+	fs.IntVar(&opts.retries, "retries", defaultRetries, "number of times docker scout is retried when the analysis of an image fails")
+	fs.DurationVar(&opts.retryDelay, "retry-delay", defaultRetryDelay, "delay before the first retry, doubled after every attempt")
+	fs.DurationVar(&opts.timeout, "timeout", defaultTimeout, "maximum duration of the analysis of an image, including retries")
+	fs.StringVar(&opts.resultsDir, "results-dir", defaultResultsDir, "directory where the SARIF report of every image is stored, emptied at startup if it was created by skout")
+	fs.BoolVar(&opts.keepSarif, "keep-sarif", false, "keep the SARIF report of every image in the results directory after the analysis instead of removing it")
+	fs.StringVar(&opts.cacheDir, "cache-dir", scan.DefaultCacheDir(), "directory where the analysis results of images pinned to a digest are cached")
+	fs.DurationVar(&opts.cacheTTL, "cache-ttl", defaultCacheTTL, "duration the analysis results of an image are cached, after which the image is analyzed again even if its digest didn't change (alias --max-age)")
+	fs.BoolVar(&opts.noCache, "no-cache", false, "analyze every image, ignoring the cached results")
+	fs.BoolVar(&opts.countOccurrences, "count-occurrences", false, "count a CVE affecting several packages of an image once per package instead of once")
+	fs.StringSliceVar(&opts.ignoreCVEs, "ignore-cve", nil, "ID of a vulnerability left out of the counts and thresholds, e.g. an accepted risk such as CVE-2023-1234, can be repeated")
+	fs.StringVar(&ignoreFile, "ignore-file", "", "file with the ID of a vulnerability to ignore per line, as with --ignore-cve, optionally followed by a comment")
+	fs.StringVar(&opts.baseline, "baseline", "", "JSON file with the known vulnerabilities of every image, left out of the counts and thresholds so that only the new ones are reported")
+	fs.BoolVar(&opts.updateBaseline, "update-baseline", false, "write the vulnerabilities found into the --baseline file instead of leaving them out")
+	fs.StringVarP(&opts.reportFormat, "report-format", "o", reportFormatTable, fmt.Sprintf("format of the report, one of: %s (alias --output)", strings.Join(reportFormats, ", ")))
+	fs.StringVar(&opts.groupBy, "group-by", "", fmt.Sprintf("group the rows of the table, only %q is supported to display a row per unique image with the number of pods running it", groupByImage))
+	fs.BoolVar(&opts.rank, "rank", false, "sort the rows of --group-by image from the image with the most critical, then high, medium and low vulnerabilities")
+	fs.BoolVar(&opts.summary, "summary", false, "only print the total number of vulnerabilities of every severity instead of the table")
+	fs.StringSliceVar(&opts.columns, "columns", nil, fmt.Sprintf("comma-separated columns of the table of the containers, in order, among: %s", strings.Join(tableColumns, ", ")))
+	fs.BoolVar(&opts.wide, "wide", false, "add the node, image registry and digest columns to the table, as with kubectl -o wide")
+	fs.BoolVar(&opts.noColor, "no-color", false, "disable the colors of the table, which are also disabled when NO_COLOR is set or stdout is not a terminal")
+	fs.StringVar(&templateFile, "template-file", "", fmt.Sprintf("file with the Go text/template rendering the report with --report-format %s", reportFormatTemplate))
+	fs.StringVar(&opts.reportFile, "report-file", "", "write the report to the given file instead of stdout")
+	fs.StringVar(&opts.metricsFile, "metrics-file", "", "also write the vulnerabilities of every container, the totals and the status of the analysis to the given file in the Prometheus text format")
+	fs.StringVar(&opts.slackWebhook, "slack-webhook", "", "URL of a Slack incoming webhook to post a summary of the results to")
+	fs.StringVar(&opts.upload, "upload", "", "object store URL, e.g. s3://bucket/prefix, to upload the SARIF reports and the report as JSON to after the analysis")
+	fs.BoolVar(&opts.stream, "stream", false, "write the result of every container to stderr as soon as the analysis of its image completes, before the final report")
+	fs.BoolVar(&opts.details, "details", false, "include the CVEs found in every image, with their severity and affected and fixed versions")
+	fs.StringVar(&opts.compare, "compare", "", "compare the results with a previous report written with --report-format json")
+	fs.BoolVar(&opts.sbom, "sbom", false, "also generate the SPDX software bill of materials of every image with docker scout sbom into the results directory")
+	fs.BoolVar(&opts.recommendations, "recommendations", false, "also get the base image recommendations of every image with docker scout recommendations and display them in the report")
+	fs.BoolVar(&opts.mergeSarif, "merge-sarif", false, fmt.Sprintf("write a single SARIF report with the results of all images to %s in the results directory", mergedSarifFilename))
+	fs.StringVar(&opts.severity, "severity", "low", fmt.Sprintf("only count and display the vulnerabilities of the given severity or higher, one of: %s", strings.Join(scan.Severities, ", ")))
+	fs.BoolVar(&opts.pruneSarif, "prune-sarif", false, "drop the vulnerabilities below --severity from the SARIF reports as soon as they are parsed, e.g. to save memory on huge reports")
+	fs.BoolVar(&opts.failOnError, "fail-on-error", true, "exit with code 1 when an image could not be analyzed, use --fail-on-error=false to only fail on the vulnerability thresholds")
+	fs.BoolVar(&opts.exitCode, "exit-code", false, "exit with code 1 if any vulnerability is found")
+	fs.StringVar(&opts.failOn, "fail-on", "", fmt.Sprintf("exit with code 1 if any vulnerability of the given severity or higher is found, one of: %s", strings.Join(scan.Severities, ", ")))
+	fs.StringVar(&opts.failOnFixable, "fail-on-fixable", "", fmt.Sprintf("exit with code 1 if any vulnerability with a fixed version of the given severity or higher is found, one of: %s", strings.Join(scan.Severities, ", ")))
+	fs.IntVar(&opts.maxCritical, "max-critical", unlimited, "exit with code 1 if more than the given number of critical vulnerabilities are found")
+	fs.IntVar(&opts.maxHigh, "max-high", unlimited, "exit with code 1 if more than the given number of high vulnerabilities are found")
+	fs.IntVar(&opts.maxMedium, "max-medium", unlimited, "exit with code 1 if more than the given number of medium vulnerabilities are found")
+	fs.IntVar(&opts.maxLow, "max-low", unlimited, "exit with code 1 if more than the given number of low vulnerabilities are found")
+	fs.IntVar(&opts.maxTotal, "max-total", unlimited, "exit with code 1 if more than the given number of vulnerabilities of any severity are found")
+	fs.IntVar(&opts.maxScore, "max-score", unlimited, "exit with code 1 if the risk score of the vulnerabilities found, weighted with --weights, is above the given score")
+	fs.StringVar(&opts.weights, "weights", defaultWeights, "comma-separated weights of the severities in the risk score shown in the report")
+	fs.SortFlags = false
+	fs.SetNormalizeFunc(normalizeFlagName)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Run "skout --help" for usage.`)
+	}
+
+	skoutArgs, scoutArgs, ignoredArgs := splitArgs(fs, args)
+	if err := fs.Parse(skoutArgs); err != nil {
+		return opts, err
+	}
+	if help {
+		fmt.Fprintf(stdout, "%s%s%s", usageHeader, fs.FlagUsages(), usageFooter)
+		return opts, pflag.ErrHelp
+	}
+	// cli holds the flags set on the command line, as opposed to those set by the config file, to check the
+	// combinations of flags the user asked for
+	cli := make(map[string]bool)
+	fs.Visit(func(f *pflag.Flag) {
+		cli[f.Name] = true
+	})
+	if err := applyConfigFile(fs, configFile, cli); err != nil {
+		return opts, err
+	}
+	opts.scoutArgs = scoutArgs
+	opts.ignoredArgs = ignoredArgs
+
+	level, err := parseLogLevel(opts.logLevel)
+	if err != nil {
+		return opts, err
+	}
+	if opts.verbose && opts.quiet {
+		return opts, errors.New("flags --verbose and --quiet are mutually exclusive, please specify only one of them")
+	}
+	if (opts.verbose || opts.quiet) && cli["log-level"] {
+		return opts, errors.New("flags --verbose and --quiet cannot be combined with --log-level")
+	}
+	if opts.verbose {
+		level = slog.LevelDebug
+	}
+	if opts.quiet {
+		level = slog.LevelError
+	}
+	opts.level = level
+
+	if !slices.Contains(logFormats, opts.logFormat) {
+		return opts, fmt.Errorf("unsupported --log-format %q, must be one of: %s", opts.logFormat, strings.Join(logFormats, ", "))
+	}
+
+	if opts.allNamespaces && opts.namespace != "" {
+		return opts, errors.New("flags --namespace and --all-namespaces are mutually exclusive, please specify only one of them")
+	}
+
+	if excludeSystem {
+		opts.excludeNamespaces = append(opts.excludeNamespaces, systemNamespaces...)
+	}
+	if len(opts.excludeNamespaces) > 0 && opts.namespace != "" {
+		return opts, errors.New("flags --exclude-namespace and --exclude-system-namespaces cannot be combined with --namespace")
+	}
+
+	if opts.inCluster && (opts.kubeConfig != "" || opts.kubeContext != "") {
+		return opts, errors.New("flag --in-cluster cannot be combined with --kubeconfig or --context")
+	}
+
+	if imagesFile != "" {
+		fileImages, err := readListFile(imagesFile)
+		if err != nil {
+			return opts, fmt.Errorf("reading --images-file: %w", err)
+		}
+		images = append(images, fileImages...)
+	}
+	for _, image := range images {
+		if image = strings.TrimSpace(image); image != "" && !slices.Contains(opts.images, image) {
+			opts.images = append(opts.images, image)
+		}
+	}
+	if (len(images) > 0 || imagesFile != "") && len(opts.images) == 0 {
+		return opts, errors.New("flags --images and --images-file must set at least one image")
+	}
+	if len(opts.images) > 0 {
+		for _, name := range clusterFlags {
+			if cli[name] {
+				return opts, fmt.Errorf("flags --images and --images-file cannot be combined with --%s", name)
+			}
+		}
+	}
+
+	if fromStdin {
+		if slices.Contains(opts.manifests, "-") {
+			return opts, errors.New("flag --from-stdin cannot be combined with --manifest -")
+		}
+		opts.manifests = append(opts.manifests, "-")
+	}
+	if len(opts.manifests) > 0 {
+		for _, name := range append(slices.Clone(imagesFlags), clusterFlags...) {
+			if cli[name] {
+				return opts, fmt.Errorf("flags --manifest and --from-stdin cannot be combined with --%s", name)
+			}
+		}
+	}
+
+	if opts.pod != "" {
+		if opts.namespace == "" {
+			return opts, errors.New("flag --pod requires --namespace")
+		}
+		for _, name := range []string{"all-namespaces", "selector", "node", "workloads", "include-all-phases"} {
+			if cli[name] {
+				return opts, fmt.Errorf("flag --pod cannot be combined with --%s", name)
+			}
+		}
+	}
+
+	if ignoreFile != "" {
+		lines, err := readListFile(ignoreFile)
+		if err != nil {
+			return opts, fmt.Errorf("reading --ignore-file: %w", err)
+		}
+		for _, line := range lines {
+			// the ID can be followed by a comment, e.g. the reason why the risk is accepted
+			opts.ignoreCVEs = append(opts.ignoreCVEs, strings.Fields(line)[0])
+		}
+	}
+	if opts.updateBaseline && opts.baseline == "" {
+		return opts, errors.New("flag --update-baseline requires --baseline")
+	}
+
+	for i, id := range opts.ignoreCVEs {
+		if opts.ignoreCVEs[i] = strings.TrimSpace(id); opts.ignoreCVEs[i] == "" {
+			return opts, errors.New("flag --ignore-cve must not be empty")
+		}
+	}
+
+	if opts.node != "" && opts.workloads {
+		return opts, errors.New("flag --node cannot be combined with --workloads, as the pod templates are not scheduled on any node")
+	}
+
+	if _, err := labels.Parse(opts.selector); err != nil {
+		return opts, fmt.Errorf("parsing --selector value: %w", err)
+	}
+
+	for _, value := range registryAuths {
+		auth, err := parseRegistryAuth(value)
+		if err != nil {
+			return opts, fmt.Errorf("parsing --registry-auth value: %w", err)
+		}
+		opts.registryAuths = append(opts.registryAuths, auth)
+	}
+
+	for _, value := range mirrors {
+		registry, mirror, ok := strings.Cut(value, "=")
+		if !ok || registry == "" || mirror == "" {
+			return opts, fmt.Errorf("invalid --registry-mirror %q, must be REGISTRY=MIRROR", value)
+		}
+		if opts.registryMirrors == nil {
+			opts.registryMirrors = make(map[string]string)
+		}
+		opts.registryMirrors[registry] = mirror
+	}
+	if !fs.Changed("scout-image") {
+		// an explicit --scout-image is pulled as is, so that it can come from another mirror
+		opts.scoutImage = scan.Mirror(opts.scoutImage, opts.registryMirrors)
+	}
+
+	for _, env := range opts.scoutEnv {
+		if name, _, ok := strings.Cut(env, "="); !ok || name == "" {
+			return opts, fmt.Errorf("invalid --scout-env %q, must be KEY=VALUE", env)
+		}
+	}
+
+	if opts.watch {
+		if opts.dryRun {
+			return opts, errors.New("flags --watch and --dry-run are mutually exclusive, please specify only one of them")
+		}
+		if opts.interval <= 0 {
+			return opts, fmt.Errorf("flag --interval must be positive, got %s", opts.interval)
+		}
+	} else if cli["interval"] {
+		return opts, errors.New("flag --interval requires --watch")
+	}
+
+	if opts.concurrency < 1 {
+		return opts, fmt.Errorf("flag --concurrency must be at least 1, got %d", opts.concurrency)
+	}
+
+	if opts.retries < 0 {
+		return opts, fmt.Errorf("flag --retries must be a non-negative integer, got %d", opts.retries)
+	}
+
+	if opts.retryDelay < 0 {
+		return opts, fmt.Errorf("flag --retry-delay must not be negative, got %s", opts.retryDelay)
+	}
+
+	if opts.timeout <= 0 {
+		return opts, fmt.Errorf("flag --timeout must be positive, got %s", opts.timeout)
+	}
+
+	if !slices.Contains(reportFormats, opts.reportFormat) {
+		return opts, fmt.Errorf("unsupported --report-format %q, must be one of: %s", opts.reportFormat, strings.Join(reportFormats, ", "))
+	}
+
+	if (opts.reportFormat == reportFormatTemplate) != (templateFile != "") {
+		return opts, fmt.Errorf("flag --template-file must be set with --report-format %s, and only with it", reportFormatTemplate)
+	}
+	if templateFile != "" {
+		if opts.template, err = parseTemplateFile(templateFile); err != nil {
+			return opts, fmt.Errorf("reading --template-file: %w", err)
+		}
+	}
+
+	if opts.upload != "" {
+		if _, _, err := parseUploadURL(opts.upload); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.scoutCommand == "" || strings.ContainsAny(opts.scoutCommand, " \t") {
+		return opts, fmt.Errorf("invalid --scout-command %q, must be a docker scout command such as %s", opts.scoutCommand, defaultScoutCommand)
+	}
+	if opts.scoutCommand != defaultScoutCommand {
+		// the vulnerabilities are only known from the SARIF report of the cves command
+		if opts.reportFormat != reportFormatTable && opts.reportFormat != reportFormatJSON {
+			return opts, fmt.Errorf("flag --scout-command %s can only be used with --report-format %s or %s", opts.scoutCommand, reportFormatTable, reportFormatJSON)
+		}
+		for _, name := range []string{"details", "merge-sarif", "compare", "summary", "group-by", "columns", "wide", "count-occurrences", "ignore-cve", "ignore-file", "baseline", "update-baseline", "prune-sarif", "metrics-file", "slack-webhook", "stream", "exit-code", "fail-on", "fail-on-fixable", "max-critical", "max-high", "max-medium", "max-low", "max-total", "max-score", "weights"} {
+			if cli[name] {
+				return opts, fmt.Errorf("flag --scout-command %s cannot be combined with --%s", opts.scoutCommand, name)
+			}
+		}
+	}
+
+	if opts.groupBy != "" && opts.groupBy != groupByImage {
+		return opts, fmt.Errorf("unsupported --group-by %q, must be: %s", opts.groupBy, groupByImage)
+	}
+
+	if opts.rank && opts.groupBy != groupByImage {
+		return opts, fmt.Errorf("flag --rank requires --group-by %s", groupByImage)
+	}
+
+	if opts.summary && (opts.reportFormat != reportFormatTable || opts.groupBy != "" || opts.details) {
+		return opts, fmt.Errorf("flag --summary can only be used with --report-format %s, without --group-by and --details", reportFormatTable)
+	}
+
+	for i, column := range opts.columns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(tableColumns, column) {
+			return opts, fmt.Errorf("unknown column %q in --columns, must be one of: %s", column, strings.Join(tableColumns, ", "))
+		}
+		if slices.Contains(opts.columns[:i], column) {
+			return opts, fmt.Errorf("duplicated column %q in --columns", column)
+		}
+		opts.columns[i] = column
+	}
+	if opts.wide {
+		if cli["columns"] {
+			return opts, errors.New("flags --wide and --columns are mutually exclusive, please specify only one of them")
+		}
+		if (opts.reportFormat != reportFormatTable && opts.reportFormat != reportFormatMarkdown) || opts.groupBy != "" || opts.summary {
+			return opts, fmt.Errorf("flag --wide can only be used with --report-format %s or %s, without --group-by and --summary", reportFormatTable, reportFormatMarkdown)
+		}
+		opts.columns = wideColumns
+	}
+	if fs.Changed("columns") {
+		if len(opts.columns) == 0 {
+			return opts, errors.New("flag --columns must set at least one column")
+		}
+		if (opts.reportFormat != reportFormatTable && opts.reportFormat != reportFormatMarkdown) || opts.groupBy != "" || opts.summary {
+			return opts, fmt.Errorf("flag --columns can only be used with --report-format %s or %s, without --group-by and --summary", reportFormatTable, reportFormatMarkdown)
+		}
+	}
+
+	opts.severity = strings.ToLower(opts.severity)
+	if !slices.Contains(scan.Severities, opts.severity) {
+		return opts, fmt.Errorf("unsupported --severity %q, must be one of: %s", opts.severity, strings.Join(scan.Severities, ", "))
+	}
+
+	thresholds, err := parseThresholds(fs, opts)
+	if err != nil {
+		return opts, err
+	}
+	opts.thresholds = thresholds
+	opts.showScore = fs.Changed("weights") || fs.Changed("max-score")
+
+	opts.fixableThresholds = newThresholds()
+	if opts.failOnFixable != "" {
+		if opts.fixableThresholds, err = failOn(opts.failOnFixable); err != nil {
+			return opts, fmt.Errorf("parsing --fail-on-fixable value: %w", err)
+		}
+	}
+
+	if opts.allNamespaces {
+		opts.namespace = v1.NamespaceAll
+	}
+
+	return opts, nil
+}
+
+// readListFile returns the values listed in the given file, one per line, e.g. images. Empty lines and lines
+// starting with # are ignored.
+func readListFile(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values = append(values, line)
+	}
+	return values, nil
+}
+
+// parseThresholds returns the thresholds set by the command line options.
+// --fail-on sets the baseline thresholds, then any explicit --max-<severity> flag overrides the
+// threshold of its own severity. --exit-code alone fails on any vulnerability.
+func parseThresholds(fs *pflag.FlagSet, opts options) (Thresholds, error) {
+	maxFlags := map[string]int{
+		"max-critical": opts.maxCritical,
+		"max-high":     opts.maxHigh,
+		"max-medium":   opts.maxMedium,
+		"max-low":      opts.maxLow,
+		"max-total":    opts.maxTotal,
+		"max-score":    opts.maxScore,
+	}
+
+	anyMax := false
+	for name, n := range maxFlags {
+		if fs.Changed(name) {
+			if n < 0 {
+				return Thresholds{}, fmt.Errorf("flag --%s must be a non-negative integer, got %d", name, n)
+			}
+			anyMax = true
+		}
+	}
+
+	failOnSev := opts.failOn
+	if failOnSev == "" && opts.exitCode && !anyMax {
+		failOnSev = "low"
+	}
+
+	thresholds := newThresholds()
+	if failOnSev != "" {
+		var err error
+		if thresholds, err = failOn(failOnSev); err != nil {
+			return thresholds, fmt.Errorf("parsing --fail-on value: %w", err)
+		}
+	}
+
+	if fs.Changed("max-critical") {
+		thresholds.Critical = opts.maxCritical
+	}
+	if fs.Changed("max-high") {
+		thresholds.High = opts.maxHigh
+	}
+	if fs.Changed("max-medium") {
+		thresholds.Medium = opts.maxMedium
+	}
+	if fs.Changed("max-low") {
+		thresholds.Low = opts.maxLow
+	}
+	if fs.Changed("max-total") {
+		thresholds.Total = opts.maxTotal
+	}
+	if fs.Changed("max-score") {
+		thresholds.Score = opts.maxScore
+	}
+
+	weights, err := parseWeights(opts.weights)
+	if err != nil {
+		return thresholds, fmt.Errorf("parsing --weights value: %w", err)
+	}
+	thresholds.Weights = weights
+
+	return thresholds, nil
+}
+
+// flagAliases maps the alternative names of some flags to their name.
+var flagAliases = map[string]string{
+	// the TTL of the cache is the maximum age of the cached results, after which the images are analyzed again
+	"max-age": "cache-ttl",
+	// the format of the report, as with kubectl -o
+	"output": "report-format",
+}
+
+// normalizeFlagName resolves the aliases of the flags, so that they can be used on the command line and in the config file.
+func normalizeFlagName(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if alias, ok := flagAliases[name]; ok {
+		name = alias
+	}
+	return pflag.NormalizedName(name)
+}
+
+// splitArgs splits the command line arguments into the ones defined in fs and the rest, which are
+// forwarded to docker scout in the same order. Everything after a "--" terminator is forwarded as is.
+// The docker scout flags used internally by skout are returned apart, along with their value, as ignoredArgs.
+func splitArgs(fs *pflag.FlagSet, args []string) (skoutArgs, scoutArgs, ignoredArgs []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			scoutArgs = append(scoutArgs, args[i+1:]...)
+			break
+		}
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			scoutArgs = append(scoutArgs, arg)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+		var flag *pflag.Flag
+		if strings.HasPrefix(arg, "--") {
+			flag = fs.Lookup(name)
+		} else if len(name) == 1 {
+			flag = fs.ShorthandLookup(name)
+		} else if flag = fs.ShorthandLookup(name[:1]); flag != nil {
+			// a group of short flags, as parsed by pflag: boolean flags combined, e.g. -Aq, followed by the value
+			// of the last one if it isn't boolean, e.g. -ojson or -lapp=web, attached or in the next argument
+			hasValue = true
+			group := strings.TrimPrefix(arg, "-")
+			for j := 0; j < len(group); j++ {
+				short := fs.ShorthandLookup(group[j : j+1])
+				if short == nil {
+					break
+				}
+				if short.NoOptDefVal == "" {
+					flag, hasValue = short, j+1 < len(group)
+					break
+				}
+			}
+		}
+
+		// the flag value is the next argument, unless the flag is a boolean one or the value is given as --flag=value
+		takesNext := !hasValue && (flag == nil || flag.NoOptDefVal == "") && i+1 < len(args)
+
+		switch {
+		case flag != nil:
+			skoutArgs = append(skoutArgs, arg)
+			if takesNext {
+				skoutArgs = append(skoutArgs, args[i+1])
+				i = i + 1
+			}
+		case slices.Contains(internalScoutFlags, name):
+			ignoredArgs = append(ignoredArgs, arg)
+			if takesNext {
+				i = i + 1
+			}
+		default:
+			scoutArgs = append(scoutArgs, arg)
+		}
+	}
+
+	return skoutArgs, scoutArgs, ignoredArgs
+}
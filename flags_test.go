@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestParseFlagsSplitsArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		scoutArgs   []string
+		ignoredArgs []string
+		check       func(opts options) bool
+	}{
+		{
+			name:      "unknown flag with separate value is forwarded",
+			args:      []string{"--platform", "linux/amd64"},
+			scoutArgs: []string{"--platform", "linux/amd64"},
+		},
+		{
+			name:      "unknown flag with attached value is forwarded",
+			args:      []string{"--platform=linux/amd64"},
+			scoutArgs: []string{"--platform=linux/amd64"},
+		},
+		{
+			name:  "flag with separate value",
+			args:  []string{"--namespace", "default"},
+			check: func(opts options) bool { return opts.namespace == "default" },
+		},
+		{
+			name:  "flag with attached value",
+			args:  []string{"--namespace=default"},
+			check: func(opts options) bool { return opts.namespace == "default" },
+		},
+		{
+			name:      "bool flag doesn't take the next argument",
+			args:      []string{"--details", "--platform", "linux/amd64"},
+			scoutArgs: []string{"--platform", "linux/amd64"},
+			check:     func(opts options) bool { return opts.details },
+		},
+		{
+			name:      "arguments after -- are forwarded",
+			args:      []string{"--details", "--", "--namespace", "default"},
+			scoutArgs: []string{"--namespace", "default"},
+			check:     func(opts options) bool { return opts.details && opts.namespace == "" },
+		},
+		{
+			name:        "internal flag is ignored along with its value",
+			args:        []string{"--format", "sarif", "--namespace", "default"},
+			ignoredArgs: []string{"--format"},
+			check:       func(opts options) bool { return opts.namespace == "default" },
+		},
+		{
+			name:        "--o is ignored",
+			args:        []string{"--o", "json"},
+			ignoredArgs: []string{"--o"},
+			check:       func(opts options) bool { return opts.reportFormat == reportFormatTable },
+		},
+		{
+			name:  "--output is an alias of --report-format",
+			args:  []string{"--output", "json"},
+			check: func(opts options) bool { return opts.reportFormat == reportFormatJSON },
+		},
+		{
+			name:  "short flag with attached value",
+			args:  []string{"-ojson"},
+			check: func(opts options) bool { return opts.reportFormat == reportFormatJSON },
+		},
+		{
+			name:  "combined short bool flags",
+			args:  []string{"-Aq"},
+			check: func(opts options) bool { return opts.allNamespaces && opts.quiet },
+		},
+		{
+			name:  "combined short flags ending with a value",
+			args:  []string{"-vo", "json"},
+			check: func(opts options) bool { return opts.verbose && opts.reportFormat == reportFormatJSON },
+		},
+		{
+			name:  "short flag with attached value holding =",
+			args:  []string{"-lapp=web"},
+			check: func(opts options) bool { return opts.selector == "app=web" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseFlags(tt.args, io.Discard, io.Discard)
+			if err != nil {
+				t.Fatalf("parseFlags(%q) returned error: %v", tt.args, err)
+			}
+			if !slices.Equal(opts.scoutArgs, tt.scoutArgs) {
+				t.Errorf("scoutArgs = %q, want %q", opts.scoutArgs, tt.scoutArgs)
+			}
+			if !slices.Equal(opts.ignoredArgs, tt.ignoredArgs) {
+				t.Errorf("ignoredArgs = %q, want %q", opts.ignoredArgs, tt.ignoredArgs)
+			}
+			if tt.check != nil && !tt.check(opts) {
+				t.Errorf("parseFlags(%q) returned unexpected options: %+v", tt.args, opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	"github.com/felipecruz91/skout/scan"
+)
+
+// reportTemplate is the template of the standalone HTML report, embedded so that the binary has no other files.
+//
+//go:embed report.html.tmpl
+var reportTemplate string
+
+// htmlTemplate is the parsed reportTemplate.
+var htmlTemplate = template.Must(template.New("report").Parse(reportTemplate))
+
+// htmlRow is a row of the containers table of the HTML report.
+type htmlRow struct {
+	Namespace       string
+	Pod             string
+	Container       string
+	Image           string
+	Vulnerabilities scan.Vulnerabilities
+	Fixable         scan.Vulnerabilities
+	Error           string
+}
+
+// writeHTML renders the report into w as a standalone HTML page with a summary and a sortable table with a row per container.
+func writeHTML(w io.Writer, report Report) error {
+	data := struct {
+		Total     scan.Vulnerabilities
+		Fixable   scan.Vulnerabilities
+		Rows      []htmlRow
+		Unscanned []UnscannedImage
+	}{Total: report.Total, Fixable: report.Fixable, Unscanned: report.Unscanned}
+
+	for _, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			data.Rows = append(data.Rows, htmlRow{
+				Namespace:       item.Namespace,
+				Pod:             item.Pod.Name,
+				Container:       container.Name,
+				Image:           container.Image,
+				Vulnerabilities: container.Vulnerabilities,
+				Fixable:         container.Fixable,
+				Error:           container.Error,
+			})
+		}
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of the containers of a namespace.
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is the result of a container, failed when its vulnerabilities exceed the thresholds.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitMessage is the message and details of a failed test case.
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit renders the report into w as a JUnit XML report with a test suite per namespace and a test case
+// per container, which fails when the vulnerabilities of the container exceed the thresholds.
+func writeJUnit(w io.Writer, report Report) error {
+	suites := junitTestSuites{Name: "skout"}
+	suiteIndex := make(map[string]int)
+
+	for _, item := range report.Items {
+		i, ok := suiteIndex[item.Namespace]
+		if !ok {
+			i = len(suites.Suites)
+			suiteIndex[item.Namespace] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: item.Namespace})
+		}
+		suite := &suites.Suites[i]
+
+		for _, container := range item.Pod.Containers {
+			summary := fmt.Sprintf("critical: %d, high: %d, medium: %d, low: %d", container.Vulnerabilities.Critical,
+				container.Vulnerabilities.High, container.Vulnerabilities.Medium, container.Vulnerabilities.Low)
+			tc := junitTestCase{
+				Name:      fmt.Sprintf("%s (%s)", container.Name, container.Image),
+				ClassName: fmt.Sprintf("%s.%s", item.Namespace, item.Pod.Name),
+				SystemOut: summary,
+			}
+
+			if container.Error != "" {
+				tc.Error = &junitMessage{Message: "analysis failed", Type: "error", Text: container.Error}
+				suite.Errors++
+			} else if breaches := report.thresholds.Breaches(container.Vulnerabilities); len(breaches) > 0 {
+				tc.Failure = &junitMessage{Message: summary, Type: "vulnerabilities", Text: strings.Join(breaches, "\n")}
+				suite.Failures++
+			}
+
+			suite.TestCases = append(suite.TestCases, tc)
+			suite.Tests++
+		}
+	}
+
+	for _, suite := range suites.Suites {
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/felipecruz91/skout/scan"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// loadingRules returns the rules to load the given kubeconfig file or, if empty, the files of the KUBECONFIG
+// environment variable merged together, or ~/.kube/config if it is not set, as kubectl does.
+func loadingRules(kubeConfig string) *clientcmd.ClientConfigLoadingRules {
+	if kubeConfig != "" {
+		return &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig}
+	}
+	return clientcmd.NewDefaultClientConfigLoadingRules()
+}
+
+// kubeConfigPaths returns the kubeconfig files loaded by the given rules that exist.
+func kubeConfigPaths(rules *clientcmd.ClientConfigLoadingRules) []string {
+	candidates := rules.GetLoadingPrecedence()
+	if rules.ExplicitPath != "" {
+		candidates = []string{rules.ExplicitPath}
+	}
+
+	var paths []string
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// restConfig returns the client configuration for the given context of the kubeconfig files loaded by
+// loadingRules, or for its current context if kubeContext is empty.
+func restConfig(kubeConfig, kubeContext string) (*rest.Config, error) {
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules(kubeConfig),
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	)
+
+	if kubeContext != "" {
+		rawConfig, err := clientConfig.RawConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := rawConfig.Contexts[kubeContext]; !ok {
+			contexts := make([]string, 0, len(rawConfig.Contexts))
+			for name := range rawConfig.Contexts {
+				contexts = append(contexts, name)
+			}
+			sort.Strings(contexts)
+
+			return nil, fmt.Errorf("context %q not found in kubeconfig files %s, available contexts are: %s", kubeContext, strings.Join(kubeConfigPaths(loadingRules(kubeConfig)), ", "), strings.Join(contexts, ", "))
+		}
+	}
+
+	return clientConfig.ClientConfig()
+}
+
+// listItems returns the items of the cluster to analyze and the client used to list them, connecting to the
+// cluster with the kubeconfig files or the in-cluster configuration set by opts, which is updated when falling
+// back to the in-cluster configuration.
+func listItems(ctx context.Context, opts *options) ([]scan.Item, kubernetes.Interface, error) {
+	var paths []string
+	if !opts.inCluster {
+		rules := loadingRules(opts.kubeConfig)
+		paths = kubeConfigPaths(rules)
+		if len(paths) == 0 {
+			// fall back to the in-cluster configuration when running in a pod without a kubeconfig file
+			if opts.kubeConfig == "" && opts.kubeContext == "" && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+				opts.inCluster = true
+			} else {
+				return nil, nil, fmt.Errorf("loading kubeconfig file: %s: %w", strings.Join(rules.GetLoadingPrecedence(), string(filepath.ListSeparator)), os.ErrNotExist)
+			}
+		}
+	}
+
+	if opts.inCluster {
+		slog.Debug("Kubernetes config", "source", "in-cluster service account")
+	} else {
+		slog.Debug("Kubernetes config", "source", "kubeconfig files", "paths", strings.Join(paths, string(filepath.ListSeparator)), "context", opts.kubeContext)
+	}
+
+	var (
+		config *rest.Config
+		err    error
+	)
+	if opts.inCluster {
+		// uses the service account token mounted in the pod
+		config, err = rest.InClusterConfig()
+	} else {
+		// uses the current context in kubeconfig unless --context is set
+		config, err = restConfig(opts.kubeConfig, opts.kubeContext)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	listOpts := v1.ListOptions{LabelSelector: opts.selector}
+	if opts.node != "" {
+		listOpts.FieldSelector = "spec.nodeName=" + opts.node
+	}
+
+	if opts.inCluster {
+		warnOwnNamespace(opts)
+	}
+
+	var items []scan.Item
+	if opts.pod != "" {
+		items, err = scan.GetPod(ctx, clientset, opts.namespace, opts.pod)
+	} else if opts.workloads {
+		items, err = scan.ListWorkloads(ctx, clientset, opts.namespace, listOpts)
+	} else {
+		items, err = scan.ListPods(ctx, clientset, opts.namespace, listOpts, opts.includeAllPhases)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items = slices.DeleteFunc(items, func(item scan.Item) bool {
+		return slices.Contains(opts.excludeNamespaces, item.Namespace)
+	})
+	return items, clientset, nil
+}
+
+// serviceAccountNamespaceFile is the file holding the namespace of the pod skout runs in, when running in a cluster.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// warnOwnNamespace logs a warning when the namespace of the pod skout runs in is analyzed, which is rarely intended
+// when skout runs as a scheduled Job analyzing the workloads of the cluster.
+func warnOwnNamespace(opts *options) {
+	b, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return
+	}
+
+	namespace := strings.TrimSpace(string(b))
+	if (opts.namespace == "" && !slices.Contains(opts.excludeNamespaces, namespace)) || opts.namespace == namespace {
+		slog.Warn("Analyzing the namespace skout runs in, use --exclude-namespace to skip it", "namespace", namespace)
+	}
+}
+
+// clusterContext returns the name of the kubeconfig context used to connect to the cluster, "in-cluster" when using the
+// in-cluster configuration, or an empty string if unknown.
+func clusterContext(opts options) string {
+	switch {
+	case opts.inCluster:
+		return "in-cluster"
+	case opts.kubeContext != "":
+		return opts.kubeContext
+	case len(opts.images) > 0 || len(opts.manifests) > 0:
+		return ""
+	}
+
+	rawConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		loadingRules(opts.kubeConfig),
+		&clientcmd.ConfigOverrides{},
+	).RawConfig()
+	if err != nil {
+		return ""
+	}
+	return rawConfig.CurrentContext
+}
+
+// readManifests returns the items of the Kubernetes manifests of the given files, "-" being the standard input.
+func readManifests(filenames []string) ([]scan.Item, error) {
+	var items []scan.Item
+	for _, filename := range filenames {
+		fileItems, err := readManifest(filename)
+		if err != nil {
+			return nil, fmt.Errorf("parsing manifest %s: %w", filename, err)
+		}
+		items = append(items, fileItems...)
+	}
+	return items, nil
+}
+
+// readManifest returns the items of the Kubernetes manifests of the given file, "-" being the standard input.
+func readManifest(filename string) ([]scan.Item, error) {
+	if filename == "-" {
+		return scan.ManifestItems(os.Stdin)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return scan.ManifestItems(f)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logLevels are the supported values of --log-level, from the most to the least verbose.
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// logFormats are the supported values of --log-format.
+var logFormats = []string{logFormatText, logFormatJSON}
+
+// parseLogLevel returns the slog level of the given --log-level value.
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		l = slog.LevelDebug
+	case "info":
+		l = slog.LevelInfo
+	case "warn":
+		l = slog.LevelWarn
+	case "error":
+		l = slog.LevelError
+	default:
+		return l, fmt.Errorf("unsupported --log-level %q, must be one of: %s", level, strings.Join(logLevels, ", "))
+	}
+	return l, nil
+}
+
+// newLogger returns a logger writing into w the records of the given level or higher, in the given format.
+func newLogger(w io.Writer, level slog.Level, format string) *slog.Logger {
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if format == logFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, handlerOpts))
+	}
+	return slog.New(slog.NewTextHandler(w, handlerOpts))
+}
@@ -2,407 +2,572 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
-	"sync"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
-	"github.com/hashicorp/go-version"
-	"github.com/jedib0t/go-pretty/v6/table"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/felipecruz91/skout/scan"
+	"github.com/spf13/pflag"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
-	// dockerDesktopMinVersion is the first version of Docker Desktop that ships the "docker scout" CLI plugin.
-	dockerDesktopMinVersion = "4.17.0"
-	// resultsDir is the host directory where the analysis SARIF files will be stored
-	resultsDir = "results"
+	// defaultResultsDir is the default host directory where the analysis SARIF files will be stored
+	defaultResultsDir = "results"
+	// defaultConcurrency is the default maximum number of images analyzed in parallel
+	defaultConcurrency = 4
+	// defaultRetries is the default number of times docker scout is retried after a failure
+	defaultRetries = 2
+	// defaultRetryDelay is the default delay before retrying docker scout for the first time
+	defaultRetryDelay = 5 * time.Second
+	// defaultTimeout is the default maximum duration of the analysis of an image
+	defaultTimeout = 5 * time.Minute
+	// mergedSarifFilename is the name of the SARIF file that combines the reports of all images
+	mergedSarifFilename = "skout.sarif.json"
+	// defaultCacheTTL is the default duration the analysis results of an image are cached
+	defaultCacheTTL = 24 * time.Hour
+	// defaultInterval is the default delay between two analyses with --watch
+	defaultInterval = 10 * time.Minute
+	// exitInterrupted is the conventional exit code of a process terminated by SIGINT
+	exitInterrupted = 130
+	// clearScreen is the ANSI escape sequence that moves the cursor to the top left corner and clears the terminal
+	clearScreen = "\033[H\033[2J"
+)
+
+// version, commit and date identify the build of skout, they are set with -ldflags "-X main.version=..." at build time.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
 )
 
 func main() {
+	os.Exit(run(os.Args, os.Stdout, os.Stderr))
+}
+
+// run runs skout with the given command line arguments, including the program name, writing the report to stdout
+// and the logs to stderr, and returns the exit code of the process.
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, err := parseFlags(args[1:], stdout, stderr)
+	if errors.Is(err, pflag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	slog.SetDefault(newLogger(stderr, opts.level, opts.logFormat))
+
+	for _, arg := range opts.ignoredArgs {
+		slog.Warn("Ignoring flag as it is used internally to generate the output", "flag", arg)
+	}
+	if len(opts.scoutArgs) > 0 {
+		slog.Info("Forwarding unknown arguments to docker scout", "args", strings.Join(opts.scoutArgs, " "))
+	}
+
+	if opts.version {
+		fmt.Fprintf(stdout, "skout version %s, commit %s, built at %s\n", version, commit, date)
+		return 0
+	}
+
+	if opts.watch {
+		return watch(opts, stdout, stderr)
+	}
+	return analyze(opts, stdout, stderr)
+}
+
+// watch analyzes the images every opts.interval, rendering the report again every time, until it is interrupted,
+// in which case it exits with code 0. The exit code of every analysis is ignored.
+func watch(opts options, stdout, stderr io.Writer) int {
+	for {
+		// the signals are only handled by analyze while it runs, so that a second Ctrl-C still terminates skout
+		if analyze(opts, stdout, stderr) == exitInterrupted {
+			return 0
+		}
+
+		slog.Info("Waiting for the next analysis", "interval", opts.interval.String())
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-ctx.Done():
+			stop()
+			return 0
+		case <-time.After(opts.interval):
+		}
+		stop()
+	}
+}
+
+// analyze analyzes the images once and writes the report, and returns the exit code of the process.
+func analyze(opts options, stdout, stderr io.Writer) int {
+	// the analysis is interrupted on Ctrl-C, reporting the images analyzed so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// color.NoColor is already set by the color package when NO_COLOR is set or stdout is not a terminal,
+	// and a report written to a file is not displayed in a terminal either, nor is a Markdown report
+	if opts.noColor || opts.reportFile != "" || opts.reportFormat == reportFormatMarkdown {
+		color.NoColor = true
+	}
+
+	slog.Debug("skout", "version", version, "commit", commit, "date", date)
+	slog.Debug("Options",
+		"namespace", opts.namespace,
+		"allNamespaces", opts.allNamespaces,
+		"selector", opts.selector,
+		"pod", opts.pod,
+		"workloads", opts.workloads,
+		"includeAllPhases", opts.includeAllPhases,
+		"concurrency", opts.concurrency,
+		"retries", opts.retries,
+		"retryDelay", opts.retryDelay.String(),
+		"timeout", opts.timeout.String(),
+		"cacheDir", opts.cacheDir,
+		"cacheTTL", opts.cacheTTL.String(),
+		"noCache", opts.noCache,
+		"thresholds", fmt.Sprintf("%+v", opts.thresholds))
 
 	var (
-		kubeConfig string
-		namespace  string
-		verbose    bool
-		scoutArgs  []string
+		items     []scan.Item
+		clientset kubernetes.Interface
+		err       error
 	)
-
-	for i := 1; i < len(os.Args); i++ {
-		if os.Args[i] == "--kubeconfig" {
-			kubeConfig = os.Args[i+1]
-			i = i + 1
-		} else if os.Args[i] == "--namespace" {
-			namespace = os.Args[i+1]
-			i = i + 1
-		} else if os.Args[i] == "-v" {
-			verbose = true
-		} else if os.Args[i] == "--format" || os.Args[i] == "--o" || os.Args[i] == "--output" {
-			log.Printf("Ignoring flag %q as it is used internally to generate the output.", os.Args[i])
-			i = i + 1
-		} else {
-			scoutArgs = append(scoutArgs, os.Args[i])
+	if len(opts.images) > 0 {
+		// the images are analyzed as is, without connecting to any cluster
+		items = scan.ImageItems(opts.images)
+	} else if len(opts.manifests) > 0 {
+		if items, err = readManifests(opts.manifests); err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		if len(items) == 0 {
+			slog.Info("No workloads found in the manifests, there is nothing to analyze")
+		}
+	} else if items, clientset, err = listItems(ctx, &opts); err != nil {
+		if ctx.Err() != nil {
+			return exitInterrupted
+		}
+		slog.Error(err.Error())
+		return 1
+	} else if len(items) == 0 {
+		kind := "pods"
+		if opts.workloads {
+			kind = "workloads"
+		}
+		namespace := opts.namespace
+		if namespace == "" {
+			namespace = "all namespaces"
+		}
+		attrs := []any{"namespace", namespace, "selector", opts.selector}
+		if opts.node != "" {
+			attrs = append(attrs, "node", opts.node)
 		}
+		if opts.pod != "" {
+			attrs = append(attrs, "pod", opts.pod)
+		}
+		slog.Info(fmt.Sprintf("No %s found matching the given options, there is nothing to analyze", kind), attrs...)
 	}
 
-	if _, err := os.Stat(resultsDir); !errors.Is(err, os.ErrNotExist) {
-		_ = os.RemoveAll(resultsDir)
+	if len(items) == 0 && opts.reportFormat == reportFormatTable && !writesOutputs(opts) {
+		// an empty table with a zero total looks like a failed analysis, while the other formats and outputs are
+		// still written so that the tools and people reading them learn that there was nothing to analyze
+		return 0
 	}
 
-	if kubeConfig == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			log.Fatal(err)
+	if collapsed := scan.DedupDigests(items); collapsed > 0 {
+		slog.Debug("Found image references resolving to the same digest as another one", "references", collapsed)
+	}
+
+	images := scan.Images(items)
+	for _, image := range images {
+		slog.Debug("Found image", "image", image)
+	}
+
+	if opts.dryRun {
+		if err := writeImageList(stdout, items); err != nil {
+			slog.Error(err.Error())
+			return 1
 		}
-		kubeConfig = filepath.Join(homeDir, ".kube", "config")
+		return 0
 	}
 
-	if verbose {
-		log.Printf("kubeconfig file path: %s", kubeConfig)
-		log.Printf("namespace: %s", namespace)
+	var previous Report
+	if opts.compare != "" {
+		// read before analyzing the images so that an invalid report fails fast
+		if previous, err = readReportFile(opts.compare); err != nil {
+			slog.Error("Reading previous report", "error", err)
+			return 1
+		}
 	}
 
-	if _, err := os.Stat(kubeConfig); errors.Is(err, os.ErrNotExist) {
-		log.Fatalf("loading kubeconfig file: %s", err)
+	var baseline Baseline
+	if opts.baseline != "" && !opts.updateBaseline {
+		// read before analyzing the images, as the previous report, so that an invalid baseline fails fast
+		if baseline, err = readBaseline(opts.baseline); err != nil {
+			slog.Error("Reading baseline", "error", err)
+			return 1
+		}
+		slog.Debug("Leaving out the vulnerabilities of the baseline", "file", opts.baseline, "images", len(baseline.Images))
 	}
 
 	var hubUser, hubPassword string
-	canUseDockerScoutCLI := canUseDockerScoutCLI()
+	canUseDockerScoutCLI, err := scan.CanUseDockerScoutCLI()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
 	if canUseDockerScoutCLI {
-		log.Printf("Will be using the docker scout CLI plugin that is shipped with Docker Desktop to analyze images")
+		slog.Info("Will be using the docker scout CLI plugin to analyze images")
 	} else {
-		log.Println("Docker Desktop 4.17 or higher is not detected in the system, will be using the image \"docker/scout-cli\" to analyze the images running in the Kubernetes cluster.")
-		log.Println("Note that the analysis will take longer as we'll be running docker scout in a container instead of using the CLI that comes with Docker Desktop 4.17 or higher.")
-		log.Println("For this reason make sure to provide \"DOCKER_SCOUT_HUB_USER\" and \"DOCKER_SCOUT_HUB_PASSWORD\" as environment variables to provide such values within the container where docker scout runs.")
+		slog.Info("Neither the docker scout CLI plugin nor Docker Desktop 4.17 or higher is detected in the system, will be using the image \"docker/scout-cli\" to analyze the images running in the Kubernetes cluster.")
+		slog.Debug("docker/scout-cli image", "image", opts.scoutImage)
+		slog.Info("Note that the analysis will take longer as we'll be running docker scout in a container instead of using the CLI that comes with Docker Desktop 4.17 or higher.")
+		slog.Info("For this reason make sure to provide \"DOCKER_SCOUT_HUB_USER\" and \"DOCKER_SCOUT_HUB_PASSWORD\" as environment variables to provide such values within the container where docker scout runs.")
 
 		hubUser = os.Getenv("DOCKER_SCOUT_HUB_USER")
 		if hubUser == "" {
-			log.Fatal("Environment variable DOCKER_SCOUT_HUB_USER is not set.")
+			slog.Error("Environment variable DOCKER_SCOUT_HUB_USER is not set.")
+			return 1
 		}
 
 		hubPassword = os.Getenv("DOCKER_SCOUT_HUB_PASSWORD")
 		if hubPassword == "" {
-			log.Fatal("Environment variable DOCKER_SCOUT_HUB_PASSWORD is not set.")
+			slog.Error("Environment variable DOCKER_SCOUT_HUB_PASSWORD is not set.")
+			return 1
 		}
 	}
 
-	// uses the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	remoteEngine, err := scan.IsRemoteDockerEngine()
 	if err != nil {
-		log.Fatal(err)
+		slog.Warn("Assuming a local docker engine", "error", err)
 	}
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		log.Fatal(err)
+	if remoteEngine {
+		slog.Info("Using a remote docker engine")
 	}
 
-	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), v1.ListOptions{})
-	if err != nil {
-		log.Fatal(err)
+	if err := prepareResultsDir(opts.resultsDir); err != nil {
+		slog.Error("Preparing results directory", "error", err)
+		return 1
 	}
-
-	var images = make(map[string]string)
-	for _, pod := range pods.Items {
-		for _, container := range pod.Spec.Containers {
-			if _, ok := images[container.Image]; !ok {
-				images[container.Image] = container.Image
-				if verbose {
-					log.Println(container.Image)
-				}
+	if !opts.keepSarif {
+		// the SARIF reports are only needed while skout runs, so the vulnerabilities of the images aren't left on disk
+		defer func() {
+			if err := removeSarifReports(opts.resultsDir); err != nil {
+				slog.Warn("Failed to remove the SARIF reports", "dir", opts.resultsDir, "error", err)
 			}
-		}
+		}()
 	}
 
-	log.Printf("Analyzing a total of %d images, this may take a few seconds...", len(images))
+	containers := 0
+	for _, item := range items {
+		containers += len(item.Pod.Containers)
+	}
+	slog.Info("Analyzing images, this may take a few seconds...", "images", len(images), "containers", containers)
 
-	if err := os.MkdirAll(resultsDir, os.ModePerm); err != nil {
-		log.Fatal(err)
+	var dockerConfigDir string
+	if !canUseDockerScoutCLI && remoteEngine {
+		slog.Warn("The registries credentials can't be mounted into the docker/scout-cli container of a remote docker engine, images from private registries may fail to be analyzed.")
+	} else if !canUseDockerScoutCLI {
+		dir, warnings, err := writeRegistryDockerConfig(opts.registryAuths)
+		if err != nil {
+			slog.Error("Writing registries credentials", "error", err)
+			return 1
+		}
+		dockerConfigDir = dir
+		for _, warning := range warnings {
+			slog.Warn(warning)
+		}
+	} else if len(opts.registryAuths) > 0 {
+		slog.Warn("Ignoring flag --registry-auth as the docker scout CLI plugin uses the credentials of \"docker login\".")
 	}
 
-	criticalVuln := 0
-	highVuln := 0
-	mediumVuln := 0
-	lowVuln := 0
+	var credentials map[string]scan.RegistryCredential
+	if clientset != nil {
+		var warnings []string
+		credentials, warnings = scan.PullSecretCredentials(ctx, clientset, items)
+		for _, warning := range warnings {
+			slog.Warn(warning)
+		}
+		slog.Debug("Found imagePullSecrets credentials", "images", len(credentials))
+	}
 
-	var wg sync.WaitGroup
+	var minSeverity string
+	if opts.pruneSarif {
+		minSeverity = opts.severity
+	}
 
-	var items []Item
+	scanner := scan.Scanner{
+		Config: scan.Config{
+			UseCLI:           canUseDockerScoutCLI,
+			Image:            opts.scoutImage,
+			HubUser:          hubUser,
+			HubPassword:      hubPassword,
+			DockerConfigDir:  dockerConfigDir,
+			ResultsDir:       opts.resultsDir,
+			RemoteEngine:     remoteEngine,
+			Args:             opts.scoutArgs,
+			Retries:          opts.retries,
+			RetryDelay:       opts.retryDelay,
+			Timeout:          opts.timeout,
+			Credentials:      credentials,
+			CountOccurrences: opts.countOccurrences,
+			IgnoreCVEs:       opts.ignoreCVEs,
+			Env:              opts.scoutEnv,
+			Mirrors:          opts.registryMirrors,
+			MinSeverity:      minSeverity,
+			Baseline:         baseline.Images,
+		},
+		Concurrency:     opts.concurrency,
+		Cache:           &scan.Cache{Dir: opts.cacheDir, TTL: opts.cacheTTL, Args: opts.scoutArgs},
+		NoCache:         opts.noCache,
+		SBOM:            opts.sbom,
+		Recommendations: opts.recommendations,
+		Command:         opts.scoutCommand,
+	}
 
-	for _, pod := range pods.Items {
-		item := Item{
-			Namespace: pod.Namespace,
-			Pod: Pod{
-				Name: pod.Name,
-			},
+	if opts.stream {
+		scanner.OnResult = func(image string, result scan.Result) {
+			if err := writeImageResult(stderr, items, image, result, opts.severity); err != nil {
+				slog.Warn("Failed to stream the result", "image", image, "error", err)
+			}
 		}
+	}
 
-		for i, c := range pod.Spec.Containers {
-			wg.Add(1)
-
-			i := i
-			c := c
-
-			item.Pod.Containers = append(item.Pod.Containers, Container{
-				Name:  c.Name,
-				Image: c.Image,
-			})
+	results := scanner.Analyze(ctx, images)
+	// a second Ctrl-C terminates skout right away while the report is written
+	interrupted := ctx.Err() != nil
+	stop()
+	if interrupted {
+		canceled := 0
+		for _, result := range results {
+			if errors.Is(result.Err, scan.ErrCanceled) {
+				canceled++
+			}
+		}
+		slog.Warn("Analysis interrupted, the report only includes the images analyzed so far", "analyzed", len(images)-canceled, "canceled", canceled, "images", len(images))
+	}
 
-			go func() {
-				defer wg.Done()
+	if dockerConfigDir != "" {
+		_ = os.RemoveAll(dockerConfigDir)
+	}
 
-				var outDir string
+	total, fixable := scan.Apply(items, results, opts.severity)
 
-				var cmd *exec.Cmd
-				var args []string
-				if canUseDockerScoutCLI {
-					args = []string{"scout", "cves"}
-					outDir = resultsDir
-				} else {
-					wd, err := os.Getwd()
-					if err != nil {
-						log.Fatal(err)
-					}
+	var (
+		// reports holds the SARIF report of every image successfully analyzed, keyed by image name
+		reports = make(map[string]scan.SarifReport)
+		// failures holds the error of every image that could not be analyzed, keyed by image name
+		failures = make(map[string]error)
+		// sbomFailures holds the error of every image whose SBOM could not be generated, keyed by image name
+		sbomFailures = make(map[string]error)
+	)
+	for image, result := range results {
+		if errors.Is(result.Err, scan.ErrCanceled) {
+			// already reported as interrupted
+			continue
+		}
+		if result.Err != nil {
+			failures[image] = result.Err
+		} else {
+			reports[image] = result.Report
+		}
+		if result.SBOMErr != nil {
+			sbomFailures[image] = result.SBOMErr
+		}
+	}
 
-					// Run the containerized version of docker scout using the docker/scout-cli image
-					args = []string{
-						"run",
-						"--rm",
-						"-e", fmt.Sprintf("DOCKER_SCOUT_HUB_USER=%s", hubUser),
-						"-e", fmt.Sprintf("DOCKER_SCOUT_HUB_PASSWORD=%s", hubPassword),
-						"-v", fmt.Sprintf("%s/%s:/tmp", wd, resultsDir),
-						"docker/scout-cli",
-						"cves"}
+	if opts.updateBaseline {
+		if err := writeBaseline(opts.baseline, newBaseline(reports)); err != nil {
+			slog.Error("Writing baseline", "error", err)
+			return 1
+		}
+		slog.Info("Baseline written", "file", opts.baseline, "images", len(reports))
+	}
 
-					outDir = "/tmp"
-				}
+	if opts.mergeSarif {
+		if err := scan.WriteMergedSarif(filepath.Join(opts.resultsDir, mergedSarifFilename), reports); err != nil {
+			slog.Error("Writing merged SARIF report", "error", err)
+			return 1
+		}
+	}
 
-				// replace the matched non-alphanumeric characters with the underscore character
-				reportFilename := regexp.MustCompile(`[^a-zA-Z-0-9]+`).ReplaceAllString(c.Image, "_") + ".sarif.json"
-				outputFile := filepath.Join(outDir, reportFilename)
-				args = append(args, scoutArgs...)
-				args = append(args, "--format", "sarif", "--output", outputFile, c.Image)
+	items = sortItems(items)
 
-				cmd = exec.Command("docker", args...)
-				if err := cmd.Run(); err != nil {
-					log.Fatal(err)
-				}
+	report := Report{Items: items, Total: total, Fixable: fixable, Unscanned: unscannedImages(results), minSeverity: opts.severity, groupBy: opts.groupBy, rank: opts.rank, sarifReports: reports, template: opts.template, thresholds: opts.thresholds, summary: opts.summary, columns: opts.columns, finishedAt: time.Now()}
+	report.Score = opts.thresholds.Weights.Score(total)
+	report.showScore = opts.showScore
+	report.Scores = make(map[string]int, len(results))
+	for image, result := range results {
+		if result.Err == nil {
+			report.Scores[image] = opts.thresholds.Weights.Score(result.Vulnerabilities.AtLeast(opts.severity))
+		}
+	}
 
-				b, err := os.ReadFile(filepath.Join(resultsDir, reportFilename))
-				if err != nil {
-					log.Fatal(err)
-				}
-				var report SarifReport
+	details := make(map[string][]scan.Finding)
+	for image, sarif := range reports {
+		details[image] = sarif.Findings(opts.severity)
+	}
+	if opts.details {
+		report.Details = details
+	}
 
-				if err := json.Unmarshal(b, &report); err != nil {
-					log.Fatal(err)
-				}
+	if opts.scoutCommand != defaultScoutCommand {
+		report.scoutCommand = opts.scoutCommand
+		report.Outputs = make(map[string]string)
+		for image, result := range results {
+			if result.Err == nil {
+				report.Outputs[image] = result.Output
+			}
+		}
+	}
 
-				for _, result := range report.Runs[0].Results {
-					for _, rule := range report.Runs[0].Tool.Driver.Rules {
-						if rule.ID == result.RuleID {
-							switch rule.Properties.CvssV3Severity {
-							case "LOW":
-								item.Pod.Containers[i].Vulnerabilities.Low += 1
-								lowVuln += 1
-							case "MEDIUM":
-								item.Pod.Containers[i].Vulnerabilities.Medium += 1
-								mediumVuln += 1
-							case "HIGH":
-								item.Pod.Containers[i].Vulnerabilities.High += 1
-								highVuln += 1
-							case "CRITICAL":
-								item.Pod.Containers[i].Vulnerabilities.Critical += 1
-								criticalVuln += 1
-							}
-							break
-						}
-					}
+	if opts.recommendations {
+		report.Recommendations = make(map[string]string)
+		for image, result := range results {
+			if result.RecommendationsErr != nil {
+				if errors.Is(result.RecommendationsErr, scan.ErrCanceled) {
+					continue
 				}
-			}()
+				slog.Warn("Failed to get the base image recommendations", "image", image, "error", result.RecommendationsErr)
+				continue
+			}
+			if result.Recommendations != "" {
+				report.Recommendations[image] = result.Recommendations
+			}
 		}
-
-		items = append(items, item)
 	}
 
-	if verbose {
-		log.Println("Waiting for all goroutines to complete")
+	if opts.compare != "" {
+		current := report
+		current.Details = details
+		comparison := compareReports(previous, current)
+		report.Comparison = &comparison
+		if comparison.Degraded() {
+			slog.Warn("Vulnerabilities increased since the previous report", "previous", opts.compare)
+		}
 	}
 
-	wg.Wait()
-
-	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
-	t := table.NewWriter()
-	t.AppendHeader(table.Row{"Namespace", "Pod", "Container (image)", "Vulnerabilities"}, rowConfigAutoMerge)
+	if opts.reportFile != "" {
+		if err := writeReportFile(opts.reportFile, opts.reportFormat, report); err != nil {
+			slog.Error("Writing report file", "error", err)
+			return 1
+		}
+		slog.Info("Report written", "file", opts.reportFile)
+	} else {
+		if opts.watch && opts.reportFormat == reportFormatTable && !color.NoColor {
+			// the previous report is cleared from the terminal, the colors being disabled when it is not a terminal
+			fmt.Fprint(stdout, clearScreen)
+		}
+		if err := writeReport(stdout, opts.reportFormat, report); err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+	}
 
-	for _, item := range items {
-		for _, container := range item.Pod.Containers {
-
-			criticalVulns := fmtVuln("C", container.Vulnerabilities.Critical)
-			highVulns := fmtVuln("H", container.Vulnerabilities.High)
-			mediumVulns := fmtVuln("M", container.Vulnerabilities.Medium)
-			lowVulns := fmtVuln("L", container.Vulnerabilities.Low)
-			totalVulns := container.Vulnerabilities.Critical + container.Vulnerabilities.High + container.Vulnerabilities.Medium + container.Vulnerabilities.Low
-			vulns := fmt.Sprintf("%s %s %s %s (%d)", criticalVulns, highVulns, mediumVulns, lowVulns, totalVulns)
-
-			t.AppendRow(table.Row{item.Namespace, item.Pod.Name, fmt.Sprintf("%s (%s)", container.Name, container.Image), vulns}, rowConfigAutoMerge)
-		}
-
-	}
-
-	totalCriticalVulns := fmtVuln("C", criticalVuln)
-	totalHighVulns := fmtVuln("H", highVuln)
-	totalMediumVulns := fmtVuln("M", mediumVuln)
-	totalLowVulns := fmtVuln("L", lowVuln)
-	totalTotalVulns := criticalVuln + highVuln + mediumVuln + lowVuln
-	totalVulnsFmt := fmt.Sprintf("%s %s %s %s (%d)", totalCriticalVulns, totalHighVulns, totalMediumVulns, totalLowVulns, totalTotalVulns)
-
-	t.AppendFooter(table.Row{"", "", "Total", totalVulnsFmt})
-	t.SetColumnConfigs([]table.ColumnConfig{
-		{Number: 1, AutoMerge: true},
-		{Number: 2, AutoMerge: true},
-	})
-	t.SetStyle(table.StyleLight)
-	t.Style().Options.SeparateRows = true
-	t.SortBy([]table.SortBy{
-		{Name: "Namespace", Mode: table.Asc},
-		{Name: "Pod", Mode: table.Asc},
-		{Name: "Container (image)", Mode: table.Asc},
-		{Name: "Vulnerabilities", Mode: table.Asc},
-	})
-	fmt.Println(t.Render())
-}
+	if opts.metricsFile != "" {
+		if err := writeMetricsFile(opts.metricsFile, report); err != nil {
+			slog.Error("Writing metrics file", "error", err)
+			return 1
+		}
+		slog.Info("Metrics written", "file", opts.metricsFile)
+	}
 
-// canUseDockerScoutCLI returns whether the user has Docker Desktop installed and comes with Docker Scout (4.17 or higher).
-func canUseDockerScoutCLI() bool {
-	canUse := false
+	// the results are still published when the analysis is interrupted, so the requests don't use its canceled
+	// context but their own timeout, so that a hung endpoint can't block the exit of skout
+	if opts.upload != "" {
+		uploadCtx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+		n, err := uploadResults(uploadCtx, opts.upload, opts.resultsDir, report)
+		cancel()
+		if err != nil {
+			slog.Error("Uploading results", "error", err)
+			return 1
+		}
+		slog.Info("Results uploaded", "url", opts.upload, "files", n)
+	}
 
-	b, err := exec.Command("docker", "version").CombinedOutput()
-	if err != nil {
-		log.Fatal(err)
+	if opts.slackWebhook != "" {
+		slackCtx, cancel := context.WithTimeout(context.Background(), slackTimeout)
+		err := postSlackMessage(slackCtx, opts.slackWebhook, slackSummary(report, clusterContext(opts), opts.namespace))
+		cancel()
+		if err != nil {
+			slog.Error("Failed to post the summary to Slack", "error", err)
+		}
 	}
 
-	var re = regexp.MustCompile(`(?m)Server: Docker Desktop (?P<version>.*) `)
-	for _, line := range strings.Split(string(b), "\n") {
-		if len(re.FindStringSubmatch(line)) == 2 {
-			detectedVersion, err := version.NewVersion(re.FindStringSubmatch(line)[1])
-			if err != nil {
-				log.Fatal(err)
-			}
+	exitStatus := 0
 
-			minVersion, _ := version.NewVersion(dockerDesktopMinVersion)
-			if detectedVersion.GreaterThanOrEqual(minVersion) {
-				log.Printf("Docker Desktop version %s is greater or equal than %s", detectedVersion, minVersion)
-				canUse = true
-				break
+	if len(failures) > 0 {
+		failedImages := make([]string, 0, len(failures))
+		for image := range failures {
+			failedImages = append(failedImages, image)
+		}
+		sort.Strings(failedImages)
+
+		slog.Error("Failed to analyze some images", "failed", len(failures), "images", len(images), "failExitCode", opts.failOnError)
+		var timedOut []string
+		for _, image := range failedImages {
+			slog.Error("Failed to analyze image", "image", image, "error", failures[image])
+			if errors.Is(failures[image], scan.ErrTimeout) {
+				timedOut = append(timedOut, image)
 			}
-
+		}
+		if len(timedOut) > 0 {
+			slog.Error("The analysis of some images timed out", "timeout", opts.timeout.String(), "images", strings.Join(timedOut, ", "))
+		}
+		if opts.failOnError {
+			exitStatus = 1
+		} else {
+			slog.Warn("Ignoring the images that could not be analyzed in the exit code as --fail-on-error is false", "failed", len(failures))
 		}
 	}
 
-	return canUse
-}
-
-func fmtVuln(severitySuffix string, count int) string {
-	var f func(format string, a ...interface{}) string
+	if len(sbomFailures) > 0 {
+		failedImages := make([]string, 0, len(sbomFailures))
+		for image := range sbomFailures {
+			failedImages = append(failedImages, image)
+		}
+		sort.Strings(failedImages)
 
-	switch severitySuffix {
-	case "C":
-		f = color.New(color.FgBlack, color.BgHiRed).SprintfFunc()
-	case "H":
-		f = color.New(color.FgBlack, color.BgHiMagenta).SprintfFunc()
-	case "M":
-		f = color.New(color.FgBlack, color.BgHiYellow).SprintfFunc()
-	case "L":
-		f = color.New(color.FgBlack, color.BgHiCyan).SprintfFunc()
+		for _, image := range failedImages {
+			slog.Error("Failed to generate the SBOM of image", "image", image, "error", sbomFailures[image])
+		}
+		if opts.failOnError {
+			exitStatus = 1
+		}
 	}
 
-	vulnText := fmt.Sprintf("  %d%s  ", count, severitySuffix)
-
-	if count == 0 {
-		return color.New(color.FgBlack).SprintfFunc()(vulnText)
+	if breaches := opts.thresholds.Breaches(total); len(breaches) > 0 {
+		for _, breach := range breaches {
+			slog.Error("Vulnerability threshold exceeded", "breach", breach)
+		}
+		exitStatus = 1
 	}
 
-	return f(vulnText)
-}
-
-type SarifReport struct {
-	Version string `json:"version"`
-	Schema  string `json:"$schema"`
-	Runs    []struct {
-		Tool struct {
-			Driver struct {
-				FullName       string `json:"fullName"`
-				InformationURI string `json:"informationUri"`
-				Name           string `json:"name"`
-				Rules          []struct {
-					ID               string `json:"id"`
-					Name             string `json:"name"`
-					ShortDescription struct {
-						Text string `json:"text"`
-					} `json:"shortDescription"`
-					HelpURI string `json:"helpUri"`
-					Help    struct {
-						Text     string `json:"text"`
-						Markdown string `json:"markdown"`
-					} `json:"help"`
-					Properties struct {
-						AffectedVersion string   `json:"affected_version"`
-						CvssV3Severity  string   `json:"cvssV3_severity"`
-						FixedVersion    string   `json:"fixed_version"`
-						Tags            []string `json:"tags"`
-					} `json:"properties,omitempty"`
-				} `json:"rules"`
-				Version string `json:"version"`
-			} `json:"driver"`
-		} `json:"tool"`
-		Results []struct {
-			RuleID    string `json:"ruleId"`
-			RuleIndex int    `json:"ruleIndex"`
-			Kind      string `json:"kind"`
-			Level     string `json:"level"`
-			Message   struct {
-				Text string `json:"text"`
-			} `json:"message"`
-			Locations []struct {
-				LogicalLocations []struct {
-					Name               string `json:"name,omitempty"`
-					FullyQualifiedName string `json:"fullyQualifiedName"`
-					Kind               string `json:"kind,omitempty"`
-				} `json:"logicalLocations"`
-			} `json:"locations"`
-		} `json:"results"`
-	} `json:"runs"`
-}
-
-type Item struct {
-	Namespace string
-	Pod       Pod
-}
+	if breaches := opts.fixableThresholds.Breaches(fixable); len(breaches) > 0 {
+		for _, breach := range breaches {
+			slog.Error("Fixable vulnerability threshold exceeded", "breach", breach)
+		}
+		exitStatus = 1
+	}
 
-type Pod struct {
-	Name       string
-	Containers []Container
-}
+	if interrupted {
+		exitStatus = exitInterrupted
+	}
 
-type Container struct {
-	Name            string
-	Image           string
-	Vulnerabilities Vulnerabilities
+	return exitStatus
 }
 
-type Vulnerabilities struct {
-	Critical int
-	High     int
-	Medium   int
-	Low      int
+// writesOutputs returns whether the options request an output besides the report printed on stdout, e.g. a file
+// or a Slack message.
+func writesOutputs(opts options) bool {
+	return opts.reportFile != "" || opts.metricsFile != "" || opts.slackWebhook != "" || opts.upload != "" || opts.updateBaseline || opts.mergeSarif
 }
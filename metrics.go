@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/felipecruz91/skout/scan"
+)
+
+// metricsLabelReplacer escapes the label values as defined by the Prometheus text exposition format.
+var metricsLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetrics writes into w the number of vulnerabilities of every container and the totals in the
+// Prometheus text exposition format, followed by the time of the analysis and the number of images that could
+// not be analyzed, so that an analysis that stops running or failing can be alerted on.
+func writeMetrics(w io.Writer, report Report) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# HELP skout_vulnerabilities Number of vulnerabilities found in the image of a container, by severity.")
+	fmt.Fprintln(bw, "# TYPE skout_vulnerabilities gauge")
+	for _, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			if container.Error != "" {
+				continue
+			}
+			labels := containerLabels(item, container)
+			for _, sev := range scan.Severities {
+				if !scan.AtLeast(sev, report.minSeverity) {
+					continue
+				}
+				fmt.Fprintf(bw, "skout_vulnerabilities{%s,severity=%q} %d\n", labels, sev, severityCount(container.Vulnerabilities, sev))
+			}
+		}
+	}
+
+	fmt.Fprintln(bw, "# HELP skout_analysis_failed Whether the analysis of the image of a container failed.")
+	fmt.Fprintln(bw, "# TYPE skout_analysis_failed gauge")
+	for _, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			failed := 0
+			if container.Error != "" {
+				failed = 1
+			}
+			fmt.Fprintf(bw, "skout_analysis_failed{%s} %d\n", containerLabels(item, container), failed)
+		}
+	}
+
+	fmt.Fprintln(bw, "# HELP skout_cluster_vulnerabilities Total number of vulnerabilities found in all the containers, by severity.")
+	fmt.Fprintln(bw, "# TYPE skout_cluster_vulnerabilities gauge")
+	for _, sev := range scan.Severities {
+		if !scan.AtLeast(sev, report.minSeverity) {
+			continue
+		}
+		fmt.Fprintf(bw, "skout_cluster_vulnerabilities{severity=%q} %d\n", sev, severityCount(report.Total, sev))
+	}
+
+	fmt.Fprintln(bw, "# HELP skout_last_run_timestamp_seconds Unix time at which the last analysis finished.")
+	fmt.Fprintln(bw, "# TYPE skout_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(bw, "skout_last_run_timestamp_seconds %d\n", report.finishedAt.Unix())
+
+	fmt.Fprintln(bw, "# HELP skout_scan_errors Number of images that could not be analyzed during the last analysis.")
+	fmt.Fprintln(bw, "# TYPE skout_scan_errors gauge")
+	fmt.Fprintf(bw, "skout_scan_errors %d\n", len(report.Unscanned))
+
+	return bw.Flush()
+}
+
+// containerLabels returns the Prometheus labels identifying the given container.
+func containerLabels(item scan.Item, container scan.Container) string {
+	return fmt.Sprintf(`namespace="%s",pod="%s",container="%s",image="%s"`,
+		metricsLabelReplacer.Replace(item.Namespace),
+		metricsLabelReplacer.Replace(item.Pod.Name),
+		metricsLabelReplacer.Replace(container.Name),
+		metricsLabelReplacer.Replace(container.Image))
+}
+
+// severityCount returns the number of vulnerabilities of the given severity.
+func severityCount(v scan.Vulnerabilities, severity string) int {
+	switch severity {
+	case "critical":
+		return v.Critical
+	case "high":
+		return v.High
+	case "medium":
+		return v.Medium
+	case "low":
+		return v.Low
+	}
+	return 0
+}
+
+// writeMetricsFile writes the metrics of the report into the given file, creating its parent directories
+// if needed. The file is replaced atomically so that a collector never reads a partially written file.
+func writeMetricsFile(filename string, report Report) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := writeMetrics(tmp, report); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
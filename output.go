@@ -0,0 +1,766 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/felipecruz91/skout/scan"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+const (
+	// reportFormatTable renders the report as a human-readable table
+	reportFormatTable = "table"
+	// reportFormatJSON renders the report as JSON
+	reportFormatJSON = "json"
+	// reportFormatCSV renders the report as CSV, with a row per container
+	reportFormatCSV = "csv"
+	// reportFormatJUnit renders the report as JUnit XML, with a test case per container
+	reportFormatJUnit = "junit"
+	// reportFormatHTML renders the report as a standalone HTML page
+	reportFormatHTML = "html"
+	// reportFormatMarkdown renders the report as a GitHub-flavored Markdown table, without colors
+	reportFormatMarkdown = "markdown"
+	// reportFormatSarif renders the SARIF reports of all images merged into a single SARIF 2.1.0 document
+	reportFormatSarif = "sarif"
+	// reportFormatTemplate renders the report with the Go text/template set with --template-file
+	reportFormatTemplate = "template"
+)
+
+// groupByImage groups the rows of the table by image, with a row per unique image instead of per container.
+const groupByImage = "image"
+
+// Columns of the table selected with --columns.
+const (
+	columnNamespace       = "namespace"
+	columnPod             = "pod"
+	columnNode            = "node"
+	columnContainer       = "container"
+	columnImage           = "image"
+	columnRegistry        = "registry"
+	columnType            = "type"
+	columnDigest          = "digest"
+	columnVulnerabilities = "vulnerabilities"
+	columnFixable         = "fixable"
+	columnDuration        = "duration"
+	// columnContainerImage is the container along with its image, type and digest, only shown by default
+	columnContainerImage = "container (image)"
+)
+
+// tableColumns lists the columns that can be selected with --columns.
+var tableColumns = []string{columnNamespace, columnPod, columnNode, columnContainer, columnImage, columnRegistry, columnType, columnDigest, columnVulnerabilities, columnFixable, columnDuration}
+
+// defaultColumns are the columns of the table unless set with --columns or --wide.
+var defaultColumns = []string{columnNamespace, columnPod, columnContainerImage, columnVulnerabilities}
+
+// wideColumns are the columns of the table with --wide.
+var wideColumns = []string{columnNamespace, columnPod, columnNode, columnContainer, columnImage, columnRegistry, columnDigest, columnVulnerabilities}
+
+// reportFormats lists the supported report formats.
+var reportFormats = []string{reportFormatTable, reportFormatJSON, reportFormatCSV, reportFormatJUnit, reportFormatHTML, reportFormatMarkdown, reportFormatSarif, reportFormatTemplate}
+
+// Report is the outcome of analyzing all the images running in the cluster.
+type Report struct {
+	Items []scan.Item          `json:"items"`
+	Total scan.Vulnerabilities `json:"total"`
+	// Fixable is the part of Total that have a fixed version
+	Fixable scan.Vulnerabilities `json:"fixable"`
+	// Score is the risk score of Total, weighted with --weights
+	Score int `json:"score"`
+	// Scores holds the risk score of every image successfully analyzed, keyed by image name
+	Scores map[string]int `json:"scores,omitempty"`
+	// Details holds the vulnerabilities found in every image, keyed by image name, when requested
+	Details map[string][]scan.Finding `json:"details,omitempty"`
+	// Comparison holds the change in the vulnerabilities since a previous report, when requested
+	Comparison *Comparison `json:"comparison,omitempty"`
+	// Unscanned lists the images that could not be analyzed, with the reason why
+	Unscanned []UnscannedImage `json:"unscanned,omitempty"`
+	// Outputs holds the raw output of the docker scout command run instead of cves on every image, keyed by image name
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Recommendations holds the base image recommendations of docker scout for every image, keyed by image name, when requested
+	Recommendations map[string]string `json:"recommendations,omitempty"`
+
+	// minSeverity is the lowest severity displayed in the report
+	minSeverity string
+	// groupBy is how the rows of the table are grouped, by container if empty
+	groupBy string
+	// rank is whether the rows of the table grouped by image are sorted from the most to the least vulnerable image
+	rank bool
+	// thresholds are the thresholds that fail the test case of a container in the JUnit report and mark its row in the tables
+	thresholds Thresholds
+	// summary is whether the table only shows the total number of vulnerabilities
+	summary bool
+	// columns are the columns of the table of the containers, in order, or the default ones if empty
+	columns []string
+	// scoutCommand is the docker scout command whose Outputs are displayed, if not cves
+	scoutCommand string
+	// sarifReports holds the SARIF report of every image successfully analyzed, keyed by image name
+	sarifReports map[string]scan.SarifReport
+	// template renders the report with --report-format template
+	template *template.Template
+	// showScore is whether the footer of the tables shows the risk score, when --weights or --max-score is set
+	showScore bool
+	// finishedAt is when the analysis finished, exported in the metrics to detect when the recurring analyses stop
+	finishedAt time.Time
+}
+
+// UnscannedImage is an image that could not be analyzed.
+type UnscannedImage struct {
+	Image  string `json:"image"`
+	Reason string `json:"reason"`
+}
+
+// unscannedImages returns the images of the given results that could not be analyzed, sorted by name.
+func unscannedImages(results map[string]scan.Result) []UnscannedImage {
+	var unscanned []UnscannedImage
+	for image, result := range results {
+		if result.Err != nil {
+			unscanned = append(unscanned, UnscannedImage{Image: image, Reason: result.Err.Error()})
+		}
+	}
+	sort.Slice(unscanned, func(i, j int) bool {
+		return unscanned[i].Image < unscanned[j].Image
+	})
+	return unscanned
+}
+
+// sortItems sorts in place the given items by namespace and pod name, and their containers by name and image,
+// and removes the duplicated pods, so that every report format lists the rows in the same order on every run.
+func sortItems(items []scan.Item) []scan.Item {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Pod.Name < items[j].Pod.Name
+	})
+
+	items = slices.CompactFunc(items, func(a, b scan.Item) bool {
+		return a.Namespace == b.Namespace && a.Pod.Name == b.Pod.Name &&
+			slices.EqualFunc(a.Pod.Containers, b.Pod.Containers, func(x, y scan.Container) bool {
+				return x.Name == y.Name && x.Image == y.Image
+			})
+	})
+
+	for _, item := range items {
+		containers := item.Pod.Containers
+		sort.SliceStable(containers, func(i, j int) bool {
+			if containers[i].Name != containers[j].Name {
+				return containers[i].Name < containers[j].Name
+			}
+			return containers[i].Image < containers[j].Image
+		})
+	}
+
+	return items
+}
+
+// writeReport renders the report into w in the given format.
+func writeReport(w io.Writer, format string, report Report) error {
+	switch format {
+	case reportFormatJSON:
+		return writeJSON(w, report)
+	case reportFormatCSV:
+		return writeCSV(w, report)
+	case reportFormatJUnit:
+		return writeJUnit(w, report)
+	case reportFormatHTML:
+		return writeHTML(w, report)
+	case reportFormatMarkdown:
+		return writeMarkdown(w, report)
+	case reportFormatSarif:
+		return writeSarif(w, report)
+	case reportFormatTemplate:
+		return writeTemplate(w, report)
+	default:
+		return writeTable(w, report)
+	}
+}
+
+// writeReportFile renders the report into the given file in the given format. The parent directories
+// of the file are created if needed and the file is overwritten if it exists.
+func writeReportFile(filename, format string, report Report) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := writeReport(f, format, report); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeImageList writes into w every unique image analyzed for the given items, along with the
+// namespace/pod/container of every container referencing it.
+func writeImageList(w io.Writer, items []scan.Item) error {
+	refs := make(map[string][]string)
+	for _, item := range items {
+		for _, container := range item.Pod.Containers {
+			refs[container.ScanRef()] = append(refs[container.ScanRef()], fmt.Sprintf("%s/%s/%s", item.Namespace, item.Pod.Name, container.Name))
+		}
+	}
+
+	images := make([]string, 0, len(refs))
+	for image := range refs {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	for _, image := range images {
+		if _, err := fmt.Fprintln(w, image); err != nil {
+			return err
+		}
+		sort.Strings(refs[image])
+		for _, ref := range refs[image] {
+			if _, err := fmt.Fprintf(w, "  %s\n", ref); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d images would be analyzed\n", len(images))
+	return err
+}
+
+// writeImageResult writes into w a line with the result of the given image for every container of the items referencing it.
+func writeImageResult(w io.Writer, items []scan.Item, image string, result scan.Result, minSeverity string) error {
+	vulns := fmtVulns(result.Vulnerabilities.AtLeast(minSeverity), minSeverity)
+	if result.Err != nil {
+		vulns = fmtError(result.Err.Error())
+	}
+
+	for _, item := range items {
+		for _, container := range item.Pod.Containers {
+			if container.ScanRef() != image {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s/%s/%s (%s): %s\n", item.Namespace, item.Pod.Name, container.Name, container.Image, vulns); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeTable renders the report as a table into w.
+func writeTable(w io.Writer, report Report) error {
+	if report.summary {
+		return writeSummary(w, report)
+	}
+
+	if report.Outputs != nil {
+		if err := writeOutputsTable(w, "docker scout "+report.scoutCommand, report.Outputs); err != nil {
+			return err
+		}
+		if len(report.Unscanned) > 0 {
+			if err := writeUnscannedTable(w, report.Unscanned); err != nil {
+				return err
+			}
+		}
+		if report.Recommendations != nil {
+			return writeOutputsTable(w, "Base image recommendations", report.Recommendations)
+		}
+		return nil
+	}
+
+	t := containersTable(report)
+	if report.groupBy == groupByImage {
+		t = imagesTable(report)
+	}
+
+	if _, err := fmt.Fprintln(w, t.Render()); err != nil {
+		return err
+	}
+
+	if report.Details != nil {
+		if err := writeDetailsTable(w, report.Details); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Unscanned) > 0 {
+		if err := writeUnscannedTable(w, report.Unscanned); err != nil {
+			return err
+		}
+	}
+
+	if report.Recommendations != nil {
+		if err := writeOutputsTable(w, "Base image recommendations", report.Recommendations); err != nil {
+			return err
+		}
+	}
+
+	if report.Comparison != nil {
+		return writeComparisonTable(w, *report.Comparison)
+	}
+
+	return nil
+}
+
+// writeMarkdown renders the table of the report into w as GitHub-flavored Markdown, followed by the images that
+// could not be analyzed, if any. The colors must be disabled with color.NoColor.
+func writeMarkdown(w io.Writer, report Report) error {
+	t := containersTable(report)
+	if report.groupBy == groupByImage {
+		t = imagesTable(report)
+	}
+
+	if _, err := fmt.Fprintln(w, t.RenderMarkdown()); err != nil {
+		return err
+	}
+
+	if len(report.Unscanned) > 0 {
+		u := table.NewWriter()
+		u.AppendHeader(table.Row{"Unscanned image", "Reason"})
+		for _, image := range report.Unscanned {
+			u.AppendRow(table.Row{image.Image, image.Reason})
+		}
+		if _, err := fmt.Fprintf(w, "\n%s\n", u.RenderMarkdown()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSummary writes into w a single line with the total number of vulnerabilities of the report.
+func writeSummary(w io.Writer, report Report) error {
+	line := "Total: " + fmtVulns(report.Total, report.minSeverity)
+	if report.Fixable.Total() > 0 {
+		line = fmt.Sprintf("%s, %d fixable", line, report.Fixable.Total())
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// containersTable returns a table with a row per container of the report, with the columns of report.columns in
+// order, or defaultColumns if empty, followed by a subtotal row per namespace when the report spans several
+// namespaces and both the namespace and the vulnerabilities are shown.
+func containersTable(report Report) table.Writer {
+	columns := report.columns
+	if len(columns) == 0 {
+		columns = defaultColumns
+	}
+
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
+	t := table.NewWriter()
+
+	header := make(table.Row, len(columns))
+	var columnConfigs []table.ColumnConfig
+	// marked is the first column not merged across rows, which marks the containers exceeding the thresholds
+	marked := len(columns) - 1
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column[:1]) + column[1:]
+		if column == columnNamespace || column == columnPod {
+			columnConfigs = append(columnConfigs, table.ColumnConfig{Number: i + 1, AutoMerge: true})
+		} else if marked > i {
+			marked = i
+		}
+	}
+	t.AppendHeader(header, rowConfigAutoMerge)
+
+	// totalsRow returns a row with the given label followed by the given vulnerabilities in the vulnerabilities and
+	// fixable columns, or nil if none of them is shown
+	totalsRow := func(namespace, label string, vulns, fixable scan.Vulnerabilities) table.Row {
+		row := make(table.Row, len(columns))
+		first := -1
+		for i, column := range columns {
+			switch column {
+			case columnNamespace:
+				row[i] = namespace
+			case columnVulnerabilities:
+				row[i] = fmtVulnsFixable(vulns, fixable, report.minSeverity)
+			case columnFixable:
+				row[i] = fmtVulns(fixable, report.minSeverity)
+			default:
+				row[i] = ""
+				continue
+			}
+			if first < 0 && column != columnNamespace {
+				first = i
+			}
+		}
+		if first < 0 {
+			return nil
+		}
+		// the label goes into the free column right before the totals if possible, or the first free one otherwise
+		for i := first - 1; i >= 0; i-- {
+			if row[i] == "" {
+				row[i] = label
+				return row
+			}
+		}
+		for i := range row {
+			if row[i] == "" {
+				row[i] = label
+				break
+			}
+		}
+		return row
+	}
+
+	namespaces := make(map[string]bool)
+	for _, item := range report.Items {
+		namespaces[item.Namespace] = true
+	}
+
+	var subtotal, subtotalFixable scan.Vulnerabilities
+	for i, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			if container.Error == "" {
+				subtotal.Add(container.Vulnerabilities)
+				subtotalFixable.Add(container.Fixable)
+			}
+
+			row := make(table.Row, len(columns))
+			for j, column := range columns {
+				switch column {
+				case columnNamespace:
+					row[j] = item.Namespace
+				case columnPod:
+					row[j] = item.Pod.Name
+				case columnNode:
+					row[j] = item.Pod.Node
+				case columnContainer:
+					row[j] = container.Name
+				case columnContainerImage:
+					name := fmt.Sprintf("%s (%s)", container.Name, container.Image)
+					if container.Type != scan.ContainerTypeRegular {
+						name = fmt.Sprintf("%s [%s]", name, container.Type)
+					}
+					if container.Digest != "" {
+						name = fmt.Sprintf("%s\n%s", name, container.Digest)
+					}
+					row[j] = name
+				case columnImage:
+					row[j] = container.Image
+				case columnRegistry:
+					row[j] = container.Registry()
+				case columnType:
+					row[j] = container.Type
+				case columnDigest:
+					row[j] = container.Digest
+				case columnDuration:
+					row[j] = time.Duration(container.Duration * float64(time.Second)).Round(100 * time.Millisecond).String()
+				case columnVulnerabilities:
+					row[j] = fmtVulnsFixable(container.Vulnerabilities, container.Fixable, report.minSeverity)
+					if container.Error != "" {
+						row[j] = fmtError(container.Error)
+					}
+				case columnFixable:
+					row[j] = ""
+					if container.Error == "" {
+						row[j] = fmtVulns(container.Fixable, report.minSeverity)
+					}
+				}
+			}
+			row[marked] = fmt.Sprint(fmtBreach(container, report.thresholds), row[marked])
+			t.AppendRow(row, rowConfigAutoMerge)
+		}
+
+		// the items are sorted by namespace, so the last item of a namespace is followed by another namespace
+		if len(namespaces) > 1 && slices.Contains(columns, columnNamespace) && (i == len(report.Items)-1 || report.Items[i+1].Namespace != item.Namespace) {
+			if row := totalsRow(item.Namespace, "Subtotal", subtotal, subtotalFixable); row != nil {
+				t.AppendRow(row)
+			}
+			subtotal, subtotalFixable = scan.Vulnerabilities{}, scan.Vulnerabilities{}
+		}
+	}
+
+	if row := totalsRow("", "Total", report.Total, report.Fixable); row != nil {
+		if i := slices.Index(columns, columnVulnerabilities); i >= 0 {
+			row[i] = fmtTotal(report)
+		}
+		t.AppendFooter(row)
+	}
+	t.SetColumnConfigs(columnConfigs)
+	t.SetStyle(table.StyleLight)
+	t.Style().Options.SeparateRows = true
+
+	return t
+}
+
+// imagesTable returns a table with a row per unique image of the report, along with the number of pods running it.
+func imagesTable(report Report) table.Writer {
+	var (
+		images []string
+		// containers holds a container of every unique image, keyed by image name
+		containers = make(map[string]scan.Container)
+		// pods holds the namespace/name of the pods running every unique image, keyed by image name
+		pods = make(map[string]map[string]bool)
+	)
+	for _, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			ref := container.ScanRef()
+			if _, ok := containers[ref]; !ok {
+				images = append(images, ref)
+				containers[ref] = container
+				pods[ref] = make(map[string]bool)
+			}
+			pods[ref][item.Namespace+"/"+item.Pod.Name] = true
+		}
+	}
+	sort.Strings(images)
+	if report.rank {
+		sort.SliceStable(images, func(i, j int) bool {
+			return moreVulnerable(containers[images[i]], containers[images[j]])
+		})
+	}
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Image", "Pods", "Vulnerabilities"})
+
+	for _, ref := range images {
+		container := containers[ref]
+
+		vulns := fmtVulnsFixable(container.Vulnerabilities, container.Fixable, report.minSeverity)
+		if container.Error != "" {
+			vulns = fmtError(container.Error)
+		}
+
+		image := container.Image
+		if container.Digest != "" {
+			image = fmt.Sprintf("%s\n%s", image, container.Digest)
+		}
+		image = fmtBreach(container, report.thresholds) + image
+
+		t.AppendRow(table.Row{image, len(pods[ref]), vulns})
+	}
+
+	t.AppendFooter(table.Row{"", "Total", fmtTotal(report)})
+	t.SetStyle(table.StyleLight)
+	t.Style().Options.SeparateRows = true
+
+	return t
+}
+
+// moreVulnerable returns whether the image of container a has more critical vulnerabilities than the one of b, or
+// as many and more high vulnerabilities, and so on down to the low ones. The images that could not be analyzed come last.
+func moreVulnerable(a, b scan.Container) bool {
+	if (a.Error == "") != (b.Error == "") {
+		return a.Error == ""
+	}
+	va, vb := a.Vulnerabilities, b.Vulnerabilities
+	switch {
+	case va.Critical != vb.Critical:
+		return va.Critical > vb.Critical
+	case va.High != vb.High:
+		return va.High > vb.High
+	case va.Medium != vb.Medium:
+		return va.Medium > vb.Medium
+	default:
+		return va.Low > vb.Low
+	}
+}
+
+// writeDetailsTable renders into w a table with the vulnerabilities found in every image.
+func writeDetailsTable(w io.Writer, details map[string][]scan.Finding) error {
+	images := make([]string, 0, len(details))
+	for image := range details {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	rowConfigAutoMerge := table.RowConfig{AutoMerge: true}
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Image", "CVE", "Severity", "Affected version", "Fixed version"}, rowConfigAutoMerge)
+
+	for _, image := range images {
+		for _, f := range details[image] {
+			fixedVersion := f.FixedVersion
+			if fixedVersion == "" {
+				fixedVersion = "not fixed"
+			}
+			t.AppendRow(table.Row{image, f.CVE, f.Severity, f.AffectedVersion, fixedVersion}, rowConfigAutoMerge)
+		}
+	}
+
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, AutoMerge: true},
+	})
+	t.SetStyle(table.StyleLight)
+
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
+// writeUnscannedTable writes into w a table with the images that could not be analyzed and the reason why.
+func writeUnscannedTable(w io.Writer, unscanned []UnscannedImage) error {
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Unscanned image", "Reason"})
+	for _, u := range unscanned {
+		t.AppendRow(table.Row{u.Image, u.Reason})
+	}
+	t.SetStyle(table.StyleLight)
+
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
+// writeOutputsTable writes into w a table with the given docker scout outputs of every image, under the given title.
+func writeOutputsTable(w io.Writer, title string, outputs map[string]string) error {
+	images := make([]string, 0, len(outputs))
+	for image := range outputs {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Image", title})
+	for _, image := range images {
+		t.AppendRow(table.Row{image, outputs[image]})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Style().Options.SeparateRows = true
+
+	_, err := fmt.Fprintln(w, t.Render())
+	return err
+}
+
+// writeSarif renders into w the SARIF reports of all the images of the report merged into a single indented
+// SARIF document, with a run per image.
+func writeSarif(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(scan.MergeSarif(report.sarifReports))
+}
+
+// writeJSON renders the report as indented JSON into w.
+func writeJSON(w io.Writer, report Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// writeCSV renders the report as CSV into w, with a row per container.
+func writeCSV(w io.Writer, report Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"namespace", "pod", "container", "image", "digest", "critical", "high", "medium", "low", "total", "fixable", "error"}); err != nil {
+		return err
+	}
+
+	for _, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			v := container.Vulnerabilities
+			if err := cw.Write([]string{
+				item.Namespace,
+				item.Pod.Name,
+				container.Name,
+				container.Image,
+				container.Digest,
+				strconv.Itoa(v.Critical),
+				strconv.Itoa(v.High),
+				strconv.Itoa(v.Medium),
+				strconv.Itoa(v.Low),
+				strconv.Itoa(v.Total()),
+				strconv.Itoa(container.Fixable.Total()),
+				container.Error,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// fmtTotal formats the total vulnerabilities of the report as fmtVulnsFixable does, followed by the risk score if shown.
+func fmtTotal(report Report) string {
+	total := fmtVulnsFixable(report.Total, report.Fixable, report.minSeverity)
+	if report.showScore {
+		total = fmt.Sprintf("%s\nscore %d", total, report.Score)
+	}
+	return total
+}
+
+// fmtVulnsFixable formats the vulnerabilities as fmtVulns does, followed by the number of fixable ones if any.
+func fmtVulnsFixable(v, fixable scan.Vulnerabilities, minSeverity string) string {
+	if fixable.Total() == 0 {
+		return fmtVulns(v, minSeverity)
+	}
+	return fmt.Sprintf("%s\n%d fixable", fmtVulns(v, minSeverity), fixable.Total())
+}
+
+// fmtVulns formats the number of vulnerabilities of every severity at or above minSeverity, followed by their total.
+func fmtVulns(v scan.Vulnerabilities, minSeverity string) string {
+	var parts []string
+	for _, severity := range scan.Severities {
+		if !scan.AtLeast(severity, minSeverity) {
+			continue
+		}
+		switch severity {
+		case "critical":
+			parts = append(parts, fmtVuln("C", v.Critical))
+		case "high":
+			parts = append(parts, fmtVuln("H", v.High))
+		case "medium":
+			parts = append(parts, fmtVuln("M", v.Medium))
+		case "low":
+			parts = append(parts, fmtVuln("L", v.Low))
+		}
+	}
+
+	return fmt.Sprintf("%s (%d)", strings.Join(parts, " "), v.Total())
+}
+
+// fmtError formats the analysis error of a container in place of its vulnerabilities.
+func fmtError(err string) string {
+	switch err {
+	case scan.ErrNotAnalyzed.Error():
+		return "not analyzed"
+	case scan.ErrCanceled.Error():
+		return "canceled"
+	}
+	return "analysis failed"
+}
+
+// fmtBreach returns a red "!" marking the row of a container whose vulnerabilities exceed the thresholds, or an empty
+// string if the container is within the thresholds or could not be analyzed.
+func fmtBreach(container scan.Container, thresholds Thresholds) string {
+	if container.Error != "" || len(thresholds.Breaches(container.Vulnerabilities)) == 0 {
+		return ""
+	}
+	return color.New(color.FgHiRed, color.Bold).Sprint("!") + " "
+}
+
+func fmtVuln(severitySuffix string, count int) string {
+	var f func(format string, a ...interface{}) string
+
+	switch severitySuffix {
+	case "C":
+		f = color.New(color.FgBlack, color.BgHiRed).SprintfFunc()
+	case "H":
+		f = color.New(color.FgBlack, color.BgHiMagenta).SprintfFunc()
+	case "M":
+		f = color.New(color.FgBlack, color.BgHiYellow).SprintfFunc()
+	case "L":
+		f = color.New(color.FgBlack, color.BgHiCyan).SprintfFunc()
+	}
+
+	vulnText := fmt.Sprintf("  %d%s  ", count, severitySuffix)
+
+	if count == 0 {
+		return color.New(color.FgBlack).SprintfFunc()(vulnText)
+	}
+
+	return f(vulnText)
+}
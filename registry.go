@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// registryAuth holds the credentials of a container registry.
+type registryAuth struct {
+	Registry string
+	Username string
+	Password string
+}
+
+// parseRegistryAuth parses a REGISTRY=USERNAME:PASSWORD value of the --registry-auth flag.
+func parseRegistryAuth(value string) (registryAuth, error) {
+	registry, credentials, ok := strings.Cut(value, "=")
+	if !ok || registry == "" {
+		return registryAuth{}, fmt.Errorf("invalid registry auth %q, must be REGISTRY=USERNAME:PASSWORD", value)
+	}
+
+	username, password, ok := strings.Cut(credentials, ":")
+	if !ok || username == "" {
+		return registryAuth{}, fmt.Errorf("invalid credentials for registry %q, must be USERNAME:PASSWORD", registry)
+	}
+
+	return registryAuth{Registry: registry, Username: username, Password: password}, nil
+}
+
+// dockerConfigAuth is an entry of the auths field of a docker config.json file.
+type dockerConfigAuth struct {
+	Auth string `json:"auth,omitempty"`
+}
+
+// dockerConfig is the subset of a docker config.json file that holds the registries credentials.
+type dockerConfig struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredsStore  string                      `json:"credsStore,omitempty"`
+	CredHelpers map[string]string           `json:"credHelpers,omitempty"`
+}
+
+// hostDockerConfigFile returns the path of the docker config.json file of the host.
+func hostDockerConfigFile() (string, error) {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, ".docker", "config.json"), nil
+}
+
+// writeRegistryDockerConfig writes into a new temporary directory a docker config.json file with the inline
+// credentials of the host docker config.json file plus the given ones, which take precedence.
+// Credentials stored in credentials stores or helpers can't be used from within the docker/scout-cli container
+// and are skipped. It returns the directory, which must be removed by the caller.
+func writeRegistryDockerConfig(auths []registryAuth) (string, []string, error) {
+	config := dockerConfig{Auths: make(map[string]dockerConfigAuth)}
+	var warnings []string
+
+	configFile, err := hostDockerConfigFile()
+	if err != nil {
+		return "", nil, err
+	}
+
+	b, err := os.ReadFile(configFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", nil, err
+	}
+
+	if err == nil {
+		var hostConfig dockerConfig
+		if err := json.Unmarshal(b, &hostConfig); err != nil {
+			return "", nil, fmt.Errorf("parsing docker config file %s: %w", configFile, err)
+		}
+
+		for registry, auth := range hostConfig.Auths {
+			if auth.Auth != "" {
+				config.Auths[registry] = auth
+			}
+		}
+
+		if hostConfig.CredsStore != "" {
+			warnings = append(warnings, fmt.Sprintf("credentials stored in the %q credentials store of %s are not available to the docker/scout-cli container, use --registry-auth instead", hostConfig.CredsStore, configFile))
+		}
+		for registry := range hostConfig.CredHelpers {
+			warnings = append(warnings, fmt.Sprintf("credentials of registry %s stored in a credentials helper are not available to the docker/scout-cli container, use --registry-auth instead", registry))
+		}
+	}
+
+	for _, auth := range auths {
+		config.Auths[auth.Registry] = dockerConfigAuth{
+			Auth: base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password)),
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "skout-docker-config-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the directory holds the credentials, so it is removed on failure rather than left to the caller
+	b, err = json.Marshal(config)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), b, 0o600); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	return dir, warnings, nil
+}
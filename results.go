@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resultsDirMarker is the file created by skout in the results directory to tell it apart from any other directory.
+const resultsDirMarker = ".skout"
+
+// prepareResultsDir empties the given results directory, or creates it if it doesn't exist.
+// A non-empty directory is only emptied if it was created by skout, so that an unrelated directory is never wiped.
+func prepareResultsDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if len(entries) > 0 {
+		if _, err := os.Stat(filepath.Join(dir, resultsDirMarker)); err != nil {
+			return fmt.Errorf("refusing to empty directory %s as it was not created by skout, remove it or use --results-dir to choose another one", dir)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, resultsDirMarker), nil, 0o644)
+}
+
+// removeSarifReports removes the SARIF report of every image from the given results directory, keeping the merged
+// SARIF report if any as it is explicitly requested.
+func removeSarifReports(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == mergedSarifFilename || !strings.HasSuffix(entry.Name(), ".sarif.json") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+package scan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CacheEntry is the analysis result of an image stored in the cache.
+type CacheEntry struct {
+	Image           string          `json:"image"`
+	ScannedAt       time.Time       `json:"scannedAt"`
+	Vulnerabilities Vulnerabilities `json:"vulnerabilities"`
+	Report          SarifReport     `json:"report"`
+}
+
+// Cache stores on disk the analysis results of images pinned to a digest, which don't change between runs.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+	// Args are the extra arguments forwarded to docker scout, which are part of the key as they change the results
+	Args []string
+}
+
+// DefaultCacheDir returns the default directory of the results cache.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "skout")
+	}
+	return filepath.Join(dir, "skout")
+}
+
+// filename returns the file of the cache entry of the given image, or an empty string if the image is not
+// pinned to a digest and therefore can't be cached.
+func (c Cache) filename(image string) string {
+	if !strings.Contains(image, "@sha256:") {
+		return ""
+	}
+
+	h := sha256.Sum256([]byte(strings.Join(append([]string{image}, c.Args...), "\x00")))
+	return filepath.Join(c.Dir, hex.EncodeToString(h[:])+".json")
+}
+
+// Get returns the cached result of the given image, if any and not older than the cache TTL.
+func (c Cache) Get(image string) (CacheEntry, bool) {
+	filename := c.filename(image)
+	if filename == "" {
+		return CacheEntry{}, false
+	}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return CacheEntry{}, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil || entry.Image != image || time.Since(entry.ScannedAt) > c.TTL {
+		return CacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// Restore writes the SARIF report of the given cache entry into the results directory, as docker scout would do.
+func (e CacheEntry) Restore(resultsDir string) error {
+	b, err := json.Marshal(e.Report)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(resultsDir, SarifFilename(e.Image)), b, 0o644)
+}
+
+// Put stores the result of the given image in the cache. Images not pinned to a digest are ignored.
+func (c Cache) Put(image string, vulns Vulnerabilities, report SarifReport) error {
+	filename := c.filename(image)
+	if filename == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(CacheEntry{
+		Image:           image,
+		ScannedAt:       time.Now(),
+		Vulnerabilities: vulns,
+		Report:          report,
+	})
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that concurrent runs never read a partially written entry
+	tmp, err := os.CreateTemp(c.Dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
@@ -0,0 +1,231 @@
+package scan
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// Severities lists the supported vulnerability severities, from the highest to the lowest.
+var Severities = []string{"critical", "high", "medium", "low"}
+
+// AtLeast returns whether severity is the same as or higher than minSeverity. An empty minSeverity is the lowest
+// severity, so that every severity is kept, while an unknown severity is never kept.
+func AtLeast(severity, minSeverity string) bool {
+	index := slices.Index(Severities, strings.ToLower(severity))
+	if index < 0 {
+		return false
+	}
+	if minSeverity == "" {
+		return true
+	}
+	return index <= slices.Index(Severities, strings.ToLower(minSeverity))
+}
+
+// Item is a pod, or the pod template of a workload, along with its containers.
+type Item struct {
+	Namespace string `json:"namespace"`
+	Pod       Pod    `json:"pod"`
+	// pullSecrets are the names of the imagePullSecrets of the pod
+	pullSecrets []string
+}
+
+// Pod holds the containers of an item.
+type Pod struct {
+	Name string `json:"name"`
+	// Node is the name of the node the pod is scheduled on, if any
+	Node       string      `json:"node,omitempty"`
+	Containers []Container `json:"containers"`
+}
+
+// Container is a container of a pod and the vulnerabilities found in its image.
+type Container struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	Type  string `json:"type"`
+	// Digest is the digest of the image the container runs, if known
+	Digest          string          `json:"digest,omitempty"`
+	Vulnerabilities Vulnerabilities `json:"vulnerabilities"`
+	// Fixable is the part of Vulnerabilities that have a fixed version
+	Fixable Vulnerabilities `json:"fixable"`
+	// Error is the reason why the image of the container could not be analyzed, if any
+	Error string `json:"error,omitempty"`
+	// ScoutVersion is the version of docker scout that analyzed the image of the container, if known
+	ScoutVersion string `json:"scoutVersion,omitempty"`
+	// Duration is how long the analysis of the image of the container took, in seconds, shared by all the
+	// containers of the same image
+	Duration float64 `json:"durationSeconds,omitempty"`
+
+	// pinned is the image reference pinned to Digest, e.g. "docker.io/library/nginx@sha256:...", if known
+	pinned string
+}
+
+// ScanRef returns the reference of the image analyzed for the container: the image pinned to its digest
+// when known, so that mutable tags are analyzed as they are running, or the image otherwise. The reference
+// is normalized, so that e.g. "nginx", "nginx:latest" and "docker.io/library/nginx" are analyzed once.
+func (c Container) ScanRef() string {
+	if c.pinned != "" {
+		return normalizeImage(c.pinned)
+	}
+	return normalizeImage(c.Image)
+}
+
+// Registry returns the host of the registry of the image of the container, e.g. "docker.io", or an empty string
+// if the image reference is invalid.
+func (c Container) Registry() string {
+	named, err := reference.ParseNormalizedNamed(c.Image)
+	if err != nil {
+		return ""
+	}
+	return reference.Domain(named)
+}
+
+// digest returns the digest of the image of the container: the one reported in the pod status, if known, or the
+// one of the image reference otherwise, e.g. "nginx@sha256:...". It returns an empty string if neither is known.
+func (c Container) digest() string {
+	if c.Digest != "" {
+		return c.Digest
+	}
+	named, err := reference.ParseNormalizedNamed(c.Image)
+	if err != nil {
+		return ""
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String()
+	}
+	return ""
+}
+
+// DedupDigests pins the containers of the given items whose images resolve to the same digest under different
+// references, e.g. a tag and its pinned form or the same image pulled from two tags, to a single reference so that
+// the image is analyzed once. It returns the number of references collapsed into another one.
+func DedupDigests(items []Item) int {
+	// refs holds the reference analyzed for every digest, the first one in lexical order so that it is deterministic
+	refs := make(map[string]string)
+	for _, item := range items {
+		for _, container := range item.Pod.Containers {
+			digest := container.digest()
+			if digest == "" {
+				continue
+			}
+			if ref, ok := refs[digest]; !ok || container.ScanRef() < ref {
+				refs[digest] = container.ScanRef()
+			}
+		}
+	}
+
+	collapsed := make(map[string]bool)
+	for i := range items {
+		for j := range items[i].Pod.Containers {
+			container := &items[i].Pod.Containers[j]
+			digest := container.digest()
+			if digest == "" || container.ScanRef() == refs[digest] {
+				continue
+			}
+			collapsed[container.ScanRef()] = true
+			container.pinned = refs[digest]
+		}
+	}
+
+	return len(collapsed)
+}
+
+// Repository returns the fully qualified name of the repository of the given image reference, without its tag nor
+// digest, e.g. "docker.io/library/nginx". Invalid references are returned as is.
+func Repository(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return named.Name()
+}
+
+// Mirror returns the given image reference pulled from the mirror of its registry, if any, the mirrors being
+// prefixes such as "mirror.example.com/dockerhub" keyed by registry, e.g. "docker.io". Images of the other registries
+// and invalid references are returned as is.
+func Mirror(image string, mirrors map[string]string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	mirror, ok := mirrors[reference.Domain(named)]
+	if !ok {
+		return image
+	}
+	return strings.TrimSuffix(mirror, "/") + strings.TrimPrefix(reference.TagNameOnly(named).String(), reference.Domain(named))
+}
+
+// normalizeImage returns the fully qualified form of the given image reference, with its registry and the
+// "latest" tag when it has neither a tag nor a digest. Invalid references are returned as is.
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+const (
+	// ContainerTypeInit is the type of the containers defined in the initContainers field of a pod
+	ContainerTypeInit = "init"
+	// ContainerTypeRegular is the type of the containers defined in the containers field of a pod
+	ContainerTypeRegular = "regular"
+	// ContainerTypeEphemeral is the type of the containers defined in the ephemeralContainers field of a pod
+	ContainerTypeEphemeral = "ephemeral"
+)
+
+// ImageItems returns an item with a container for every given image, to analyze images that don't run in a cluster.
+// The namespace, pod and container names are set to "-".
+func ImageItems(images []string) []Item {
+	items := make([]Item, 0, len(images))
+	for _, image := range images {
+		items = append(items, Item{
+			Namespace: "-",
+			Pod: Pod{
+				Name:       "-",
+				Containers: []Container{{Name: "-", Image: image, Type: ContainerTypeRegular}},
+			},
+		})
+	}
+	return items
+}
+
+// Vulnerabilities holds the number of vulnerabilities by severity.
+type Vulnerabilities struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+}
+
+// Add accumulates the given vulnerabilities into v.
+func (v *Vulnerabilities) Add(o Vulnerabilities) {
+	v.Critical += o.Critical
+	v.High += o.High
+	v.Medium += o.Medium
+	v.Low += o.Low
+}
+
+// AtLeast returns the vulnerabilities of v whose severity is the same as or higher than minSeverity.
+func (v Vulnerabilities) AtLeast(minSeverity string) Vulnerabilities {
+	var filtered Vulnerabilities
+	if AtLeast("critical", minSeverity) {
+		filtered.Critical = v.Critical
+	}
+	if AtLeast("high", minSeverity) {
+		filtered.High = v.High
+	}
+	if AtLeast("medium", minSeverity) {
+		filtered.Medium = v.Medium
+	}
+	if AtLeast("low", minSeverity) {
+		filtered.Low = v.Low
+	}
+	return filtered
+}
+
+// Total returns the number of vulnerabilities across all severities.
+func (v Vulnerabilities) Total() int {
+	return v.Critical + v.High + v.Medium + v.Low
+}
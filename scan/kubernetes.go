@@ -0,0 +1,226 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrNamespaceNotFound is returned when listing the items of a namespace that doesn't exist.
+var ErrNamespaceNotFound = errors.New("namespace not found")
+
+// checkNamespace returns ErrNamespaceNotFound if the given namespace is set and doesn't exist, so that a typo is not
+// mistaken for a namespace without pods. It returns nil if the namespace can't be read, e.g. because the service
+// account is not allowed to get namespaces.
+func checkNamespace(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+
+	_, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%w: %q", ErrNamespaceNotFound, namespace)
+	}
+	if err != nil {
+		slog.Debug("Skipping namespace check", "namespace", namespace, "error", err)
+	}
+	return nil
+}
+
+// podsPageSize is the maximum number of pods listed per request, so that namespaces with thousands of pods are listed
+// in several pages rather than in a single huge response.
+const podsPageSize = 500
+
+// ListPods returns an item for every pod in the given namespace that matches listOpts, or ErrNamespaceNotFound if
+// the namespace doesn't exist. Unless allPhases is set, only the pods in the Running phase are returned, as the images
+// of completed, failed or evicted pods may no longer exist.
+func ListPods(ctx context.Context, clientset kubernetes.Interface, namespace string, listOpts v1.ListOptions, allPhases bool) ([]Item, error) {
+	if err := checkNamespace(ctx, clientset, namespace); err != nil {
+		return nil, err
+	}
+
+	if !allPhases {
+		// the other pods are filtered out by the API server rather than listed for nothing
+		listOpts.FieldSelector = strings.Trim(listOpts.FieldSelector+",status.phase="+string(corev1.PodRunning), ",")
+	}
+	if listOpts.Limit == 0 {
+		listOpts.Limit = podsPageSize
+	}
+
+	var items []Item
+	for {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, listOpts)
+		if err != nil {
+			return nil, fmt.Errorf("listing pods: %w", err)
+		}
+
+		for _, pod := range pods.Items {
+			if !allPhases && pod.Status.Phase != corev1.PodRunning {
+				slog.Debug("Skipping pod not running", "namespace", pod.Namespace, "pod", pod.Name, "phase", pod.Status.Phase)
+				continue
+			}
+			items = append(items, newPodItem(pod))
+		}
+
+		if pods.Continue == "" {
+			return items, nil
+		}
+		listOpts.Continue = pods.Continue
+	}
+}
+
+// GetPod returns the item of the given pod, whatever its phase, or an error if it doesn't exist.
+func GetPod(ctx context.Context, clientset kubernetes.Interface, namespace, name string) ([]Item, error) {
+	if err := checkNamespace(ctx, clientset, namespace); err != nil {
+		return nil, err
+	}
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("pod %q not found in namespace %q", name, namespace)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting pod: %w", err)
+	}
+
+	return []Item{newPodItem(*pod)}, nil
+}
+
+// ListWorkloads returns an item for every Deployment, StatefulSet, DaemonSet, CronJob and Job defined in the given namespace
+// that matches listOpts, built from their pod templates so that workloads without running pods are included as well.
+// It returns ErrNamespaceNotFound if the namespace doesn't exist.
+func ListWorkloads(ctx context.Context, clientset kubernetes.Interface, namespace string, listOpts v1.ListOptions) ([]Item, error) {
+	if err := checkNamespace(ctx, clientset, namespace); err != nil {
+		return nil, err
+	}
+
+	var items []Item
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments: %w", err)
+	}
+	for _, d := range deployments.Items {
+		items = append(items, newItem(d.Namespace, "Deployment/"+d.Name, d.Spec.Template.Spec))
+	}
+
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing statefulsets: %w", err)
+	}
+	for _, s := range statefulSets.Items {
+		items = append(items, newItem(s.Namespace, "StatefulSet/"+s.Name, s.Spec.Template.Spec))
+	}
+
+	daemonSets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing daemonsets: %w", err)
+	}
+	for _, ds := range daemonSets.Items {
+		items = append(items, newItem(ds.Namespace, "DaemonSet/"+ds.Name, ds.Spec.Template.Spec))
+	}
+
+	cronJobs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing cronjobs: %w", err)
+	}
+	for _, cj := range cronJobs.Items {
+		items = append(items, newItem(cj.Namespace, "CronJob/"+cj.Name, cj.Spec.JobTemplate.Spec.Template.Spec))
+	}
+
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+	for _, j := range jobs.Items {
+		// the jobs created by a CronJob are already covered by its job template
+		if owner := v1.GetControllerOf(&j); owner != nil && owner.Kind == "CronJob" {
+			continue
+		}
+		items = append(items, newItem(j.Namespace, "Job/"+j.Name, j.Spec.Template.Spec))
+	}
+
+	return items, nil
+}
+
+// newPodItem returns an item for the given pod, with the node it is scheduled on and the containers pinned to the
+// image digests reported in the pod status.
+func newPodItem(pod corev1.Pod) Item {
+	item := newItem(pod.Namespace, pod.Name, pod.Spec)
+	item.Pod.Node = pod.Spec.NodeName
+
+	imageIDs := make(map[string]string)
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+		for _, status := range statuses {
+			imageIDs[status.Name] = status.ImageID
+		}
+	}
+
+	for i := range item.Pod.Containers {
+		c := &item.Pod.Containers[i]
+		c.pinned, c.Digest = pinnedImage(imageIDs[c.Name])
+	}
+
+	return item
+}
+
+// pinnedImage returns the image reference pinned to its digest and the digest itself from the imageID of a
+// container status, e.g. "docker.io/library/nginx@sha256:...". It returns empty values if the imageID doesn't
+// refer to an image in a registry, as it happens with images built locally.
+func pinnedImage(imageID string) (string, string) {
+	imageID = strings.TrimPrefix(imageID, "docker-pullable://")
+
+	_, digest, ok := strings.Cut(imageID, "@")
+	if !ok || !strings.HasPrefix(digest, "sha256:") {
+		return "", ""
+	}
+
+	return imageID, digest
+}
+
+// newItem returns an item with a container for every init, regular and ephemeral container defined in the given pod spec.
+func newItem(namespace, name string, spec corev1.PodSpec) Item {
+	item := Item{
+		Namespace: namespace,
+		Pod: Pod{
+			Name: name,
+		},
+	}
+
+	for _, s := range spec.ImagePullSecrets {
+		item.pullSecrets = append(item.pullSecrets, s.Name)
+	}
+
+	for _, c := range spec.InitContainers {
+		item.Pod.Containers = append(item.Pod.Containers, Container{
+			Name:  c.Name,
+			Image: c.Image,
+			Type:  ContainerTypeInit,
+		})
+	}
+
+	for _, c := range spec.Containers {
+		item.Pod.Containers = append(item.Pod.Containers, Container{
+			Name:  c.Name,
+			Image: c.Image,
+			Type:  ContainerTypeRegular,
+		})
+	}
+
+	for _, c := range spec.EphemeralContainers {
+		item.Pod.Containers = append(item.Pod.Containers, Container{
+			Name:  c.Name,
+			Image: c.Image,
+			Type:  ContainerTypeEphemeral,
+		})
+	}
+
+	return item
+}
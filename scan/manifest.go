@@ -0,0 +1,152 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// ManifestItems returns an item for every Pod, Deployment, StatefulSet, DaemonSet, ReplicaSet, ReplicationController,
+// Job and CronJob of the given stream of YAML documents separated by "---", e.g. the output of "helm template" or
+// "kustomize build", to analyze their images before they are applied. The other kinds are ignored. The namespace of
+// the resources without one is set to "-". The List documents, e.g. the output of "kubectl get pods -o json", and the
+// typed lists such as PodList are expanded into their items.
+func ManifestItems(r io.Reader) ([]Item, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+
+	var items []Item
+	for i := 1; ; i++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading document %d: %w", i, err)
+		}
+
+		docItems, err := manifestItems(doc, "")
+		if err != nil {
+			return nil, fmt.Errorf("parsing document %d: %w", i, err)
+		}
+		items = append(items, docItems...)
+	}
+}
+
+// manifestItems returns the items of the given YAML document, expanding the lists into their items. The kind of the
+// document defaults to the given kind, set to the kind of the items of a typed list such as PodList as they omit it.
+func manifestItems(doc []byte, kind string) ([]Item, error) {
+	var list struct {
+		v1.TypeMeta `json:",inline"`
+		Items       []json.RawMessage `json:"items"`
+	}
+	if err := yaml.Unmarshal(doc, &list); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(list.Kind, "List") {
+		item, ok, err := manifestItem(doc, kind)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []Item{item}, nil
+	}
+
+	var items []Item
+	for i, raw := range list.Items {
+		listItems, err := manifestItems(raw, strings.TrimSuffix(list.Kind, "List"))
+		if err != nil {
+			return nil, fmt.Errorf("parsing item %d of %s: %w", i+1, list.Kind, err)
+		}
+		items = append(items, listItems...)
+	}
+	return items, nil
+}
+
+// manifestItem returns the item of the given YAML document, whose kind defaults to the given kind, or false if it is
+// empty or of a kind without a pod spec.
+func manifestItem(doc []byte, kind string) (Item, bool, error) {
+	var meta struct {
+		v1.TypeMeta `json:",inline"`
+		Metadata    v1.ObjectMeta `json:"metadata"`
+	}
+	if err := yaml.Unmarshal(doc, &meta); err != nil {
+		return Item{}, false, err
+	}
+	if meta.Kind == "" {
+		meta.Kind = kind
+	}
+
+	namespace := meta.Metadata.Namespace
+	if namespace == "" {
+		namespace = "-"
+	}
+
+	var spec corev1.PodSpec
+	switch meta.Kind {
+	case "Pod":
+		var pod corev1.Pod
+		if err := yaml.Unmarshal(doc, &pod); err != nil {
+			return Item{}, false, err
+		}
+		// the pods dumped with kubectl hold their node and the digests of their images, as those listed from the cluster
+		pod.Namespace = namespace
+		return newPodItem(pod), true, nil
+	case "Deployment":
+		var d appsv1.Deployment
+		if err := yaml.Unmarshal(doc, &d); err != nil {
+			return Item{}, false, err
+		}
+		spec = d.Spec.Template.Spec
+	case "StatefulSet":
+		var s appsv1.StatefulSet
+		if err := yaml.Unmarshal(doc, &s); err != nil {
+			return Item{}, false, err
+		}
+		spec = s.Spec.Template.Spec
+	case "DaemonSet":
+		var ds appsv1.DaemonSet
+		if err := yaml.Unmarshal(doc, &ds); err != nil {
+			return Item{}, false, err
+		}
+		spec = ds.Spec.Template.Spec
+	case "ReplicaSet":
+		var rs appsv1.ReplicaSet
+		if err := yaml.Unmarshal(doc, &rs); err != nil {
+			return Item{}, false, err
+		}
+		spec = rs.Spec.Template.Spec
+	case "ReplicationController":
+		var rc corev1.ReplicationController
+		if err := yaml.Unmarshal(doc, &rc); err != nil {
+			return Item{}, false, err
+		}
+		if rc.Spec.Template == nil {
+			return Item{}, false, nil
+		}
+		spec = rc.Spec.Template.Spec
+	case "Job":
+		var j batchv1.Job
+		if err := yaml.Unmarshal(doc, &j); err != nil {
+			return Item{}, false, err
+		}
+		spec = j.Spec.Template.Spec
+	case "CronJob":
+		var cj batchv1.CronJob
+		if err := yaml.Unmarshal(doc, &cj); err != nil {
+			return Item{}, false, err
+		}
+		spec = cj.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return Item{}, false, nil
+	}
+
+	return newItem(namespace, meta.Kind+"/"+meta.Metadata.Name, spec), true, nil
+}
@@ -0,0 +1,128 @@
+package scan
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RegistryCredential holds the credentials used by docker scout to pull an image from its registry.
+type RegistryCredential struct {
+	Username string
+	Password string
+}
+
+// pullSecretAuth is an entry of the auths of a .dockerconfigjson or .dockercfg secret.
+type pullSecretAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+// PullSecretCredentials returns the credentials of every image of the given items, keyed by image name, found in
+// the imagePullSecrets of their pods for the registry of the image. Secrets that can't be read, e.g. because the
+// service account is not allowed to get secrets, are skipped and returned as warnings.
+func PullSecretCredentials(ctx context.Context, clientset kubernetes.Interface, items []Item) (map[string]RegistryCredential, []string) {
+	var (
+		credentials = make(map[string]RegistryCredential)
+		warnings    []string
+		// secrets holds the auths of every secret already read, keyed by namespace/name
+		secrets = make(map[string]map[string]pullSecretAuth)
+	)
+
+	for _, item := range items {
+		for _, name := range item.pullSecrets {
+			key := item.Namespace + "/" + name
+			auths, ok := secrets[key]
+			if !ok {
+				var err error
+				if auths, err = readPullSecret(ctx, clientset, item.Namespace, name); err != nil {
+					warnings = append(warnings, fmt.Sprintf("Ignoring imagePullSecret %s: %s", key, err))
+				}
+				secrets[key] = auths
+			}
+
+			for _, container := range item.Pod.Containers {
+				image := container.ScanRef()
+				if _, ok := credentials[image]; ok {
+					continue
+				}
+				if credential, ok := auths[registryHost(image)]; ok {
+					credentials[image] = credential.credential()
+				}
+			}
+		}
+	}
+
+	return credentials, warnings
+}
+
+// readPullSecret returns the auths of the given image pull secret, keyed by registry host.
+func readPullSecret(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (map[string]pullSecretAuth, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var auths map[string]pullSecretAuth
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var config struct {
+			Auths map[string]pullSecretAuth `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", corev1.DockerConfigJsonKey, err)
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", corev1.DockerConfigKey, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported secret type %s", secret.Type)
+	}
+
+	byHost := make(map[string]pullSecretAuth, len(auths))
+	for registry, auth := range auths {
+		byHost[normalizeRegistryHost(registry)] = auth
+	}
+	return byHost, nil
+}
+
+// credential returns the username and password of the auth, decoding them from the auth field if needed.
+func (a pullSecretAuth) credential() RegistryCredential {
+	if a.Username == "" && a.Auth != "" {
+		if b, err := base64.StdEncoding.DecodeString(a.Auth); err == nil {
+			username, password, _ := strings.Cut(string(b), ":")
+			return RegistryCredential{Username: username, Password: password}
+		}
+	}
+	return RegistryCredential{Username: a.Username, Password: a.Password}
+}
+
+// registryHost returns the host of the registry of the given image reference, "docker.io" for Docker Hub images.
+func registryHost(image string) string {
+	first, _, ok := strings.Cut(image, "/")
+	if !ok || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		return "docker.io"
+	}
+	return normalizeRegistryHost(first)
+}
+
+// normalizeRegistryHost returns the host of a registry as found in a docker config file, e.g.
+// "https://index.docker.io/v1/" is "docker.io".
+func normalizeRegistryHost(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	registry, _, _ = strings.Cut(registry, "/")
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return registry
+}
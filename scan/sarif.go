@@ -0,0 +1,301 @@
+package scan
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// sarifVersion is the version of the SARIF format of the merged report
+	sarifVersion = "2.1.0"
+	// sarifSchema is the JSON schema of the SARIF format of the merged report
+	sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type SarifReport struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+type SarifRun struct {
+	Tool struct {
+		Driver struct {
+			FullName       string      `json:"fullName"`
+			InformationURI string      `json:"informationUri"`
+			Name           string      `json:"name"`
+			Rules          []SarifRule `json:"rules"`
+			Version        string      `json:"version"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []SarifResult `json:"results"`
+	// AutomationDetails identifies the image the run belongs to in a merged report
+	AutomationDetails *SarifAutomationDetails `json:"automationDetails,omitempty"`
+}
+
+type SarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type SarifRule struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	ShortDescription struct {
+		Text string `json:"text"`
+	} `json:"shortDescription"`
+	HelpURI string `json:"helpUri"`
+	Help    struct {
+		Text     string `json:"text"`
+		Markdown string `json:"markdown"`
+	} `json:"help"`
+	Properties struct {
+		AffectedVersion string   `json:"affected_version"`
+		CvssV3Severity  string   `json:"cvssV3_severity"`
+		FixedVersion    string   `json:"fixed_version"`
+		Tags            []string `json:"tags"`
+	} `json:"properties,omitempty"`
+}
+
+type SarifResult struct {
+	RuleID    string `json:"ruleId"`
+	RuleIndex int    `json:"ruleIndex"`
+	Kind      string `json:"kind"`
+	Level     string `json:"level"`
+	Message   struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []struct {
+		LogicalLocations []struct {
+			Name               string `json:"name,omitempty"`
+			FullyQualifiedName string `json:"fullyQualifiedName"`
+			Kind               string `json:"kind,omitempty"`
+		} `json:"logicalLocations"`
+	} `json:"locations"`
+}
+
+// Rule returns the rule the given result refers to. It is looked up by the result ruleIndex first,
+// and by its ruleId if the index does not point to a rule with the same ID.
+func (r SarifRun) Rule(result SarifResult) (SarifRule, bool) {
+	rules := r.Tool.Driver.Rules
+	if result.RuleIndex >= 0 && result.RuleIndex < len(rules) && rules[result.RuleIndex].ID == result.RuleID {
+		return rules[result.RuleIndex], true
+	}
+
+	for _, rule := range rules {
+		if rule.ID == result.RuleID {
+			return rule, true
+		}
+	}
+
+	return SarifRule{}, false
+}
+
+// Severity returns the severity of the given result (CRITICAL, HIGH, MEDIUM or LOW) as reported in the
+// cvssV3_severity property of its rule. If the rule has no severity, it is derived from the result level.
+func (r SarifRun) Severity(result SarifResult) string {
+	if rule, ok := r.Rule(result); ok && rule.Properties.CvssV3Severity != "" {
+		return strings.ToUpper(rule.Properties.CvssV3Severity)
+	}
+
+	switch result.Level {
+	case "error":
+		return "HIGH"
+	case "warning":
+		return "MEDIUM"
+	case "note":
+		return "LOW"
+	}
+
+	return ""
+}
+
+// ToolVersion returns the version of docker scout that generated the first run of the report, or an empty string
+// if it is unknown.
+func (r SarifReport) ToolVersion() string {
+	if len(r.Runs) == 0 {
+		return ""
+	}
+	return r.Runs[0].Tool.Driver.Version
+}
+
+// Vulnerabilities returns the number of vulnerabilities by severity found in the first run of the report, along with
+// the number of them that have a fixed version. A CVE affecting several packages is counted once, unless
+// countOccurrences is set to count every result of the run.
+func (r SarifReport) Vulnerabilities(countOccurrences bool) (total, fixable Vulnerabilities) {
+	if len(r.Runs) == 0 {
+		return total, fixable
+	}
+
+	run := r.Runs[0]
+	seen := make(map[string]bool)
+	for _, result := range run.Results {
+		if !countOccurrences {
+			if seen[result.RuleID] {
+				continue
+			}
+			seen[result.RuleID] = true
+		}
+
+		var v Vulnerabilities
+		switch run.Severity(result) {
+		case "LOW":
+			v.Low = 1
+		case "MEDIUM":
+			v.Medium = 1
+		case "HIGH":
+			v.High = 1
+		case "CRITICAL":
+			v.Critical = 1
+		}
+
+		total.Add(v)
+		if rule, ok := run.Rule(result); ok && IsFixed(rule.Properties.FixedVersion) {
+			fixable.Add(v)
+		}
+	}
+
+	return total, fixable
+}
+
+// Without returns a copy of the report without the results of the given rule IDs, e.g. CVE-2023-1234, compared
+// case-insensitively.
+func (r SarifReport) Without(ruleIDs []string) SarifReport {
+	if len(ruleIDs) == 0 {
+		return r
+	}
+
+	ignored := make(map[string]bool, len(ruleIDs))
+	for _, id := range ruleIDs {
+		ignored[strings.ToUpper(id)] = true
+	}
+
+	filtered := r
+	filtered.Runs = make([]SarifRun, len(r.Runs))
+	for i, run := range r.Runs {
+		results := make([]SarifResult, 0, len(run.Results))
+		for _, result := range run.Results {
+			if !ignored[strings.ToUpper(result.RuleID)] {
+				results = append(results, result)
+			}
+		}
+		run.Results = results
+		filtered.Runs[i] = run
+	}
+	return filtered
+}
+
+// RuleIDs returns the sorted IDs of the rules of the results of the report, e.g. CVE-2023-1234, once per rule.
+func (r SarifReport) RuleIDs() []string {
+	var ids []string
+	for _, run := range r.Runs {
+		for _, result := range run.Results {
+			ids = append(ids, result.RuleID)
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// AtLeast returns a copy of the report with only the results whose severity is the same as or higher than
+// minSeverity, so that the lower ones are neither counted nor kept in memory.
+func (r SarifReport) AtLeast(minSeverity string) SarifReport {
+	filtered := r
+	filtered.Runs = make([]SarifRun, len(r.Runs))
+	for i, run := range r.Runs {
+		results := make([]SarifResult, 0, len(run.Results))
+		for _, result := range run.Results {
+			if severity := run.Severity(result); severity != "" && AtLeast(severity, minSeverity) {
+				results = append(results, result)
+			}
+		}
+		run.Results = results
+		filtered.Runs[i] = run
+	}
+	return filtered
+}
+
+// IsFixed returns whether the given fixed version of a vulnerability refers to an actual version.
+func IsFixed(fixedVersion string) bool {
+	return fixedVersion != "" && !strings.EqualFold(fixedVersion, "not fixed")
+}
+
+// Finding is a vulnerability found in an image.
+type Finding struct {
+	CVE             string `json:"cve"`
+	Severity        string `json:"severity"`
+	AffectedVersion string `json:"affectedVersion"`
+	FixedVersion    string `json:"fixedVersion"`
+}
+
+// Findings returns the vulnerabilities found in the report whose severity is the same as or higher than
+// minSeverity, once per rule, sorted from the highest to the lowest severity and then by CVE.
+func (r SarifReport) Findings(minSeverity string) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+
+	for _, run := range r.Runs {
+		for _, result := range run.Results {
+			severity := run.Severity(result)
+			if severity == "" || !AtLeast(severity, minSeverity) || seen[result.RuleID] {
+				continue
+			}
+			seen[result.RuleID] = true
+
+			rule, _ := run.Rule(result)
+			findings = append(findings, Finding{
+				CVE:             result.RuleID,
+				Severity:        severity,
+				AffectedVersion: rule.Properties.AffectedVersion,
+				FixedVersion:    rule.Properties.FixedVersion,
+			})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		ri := slices.Index(Severities, strings.ToLower(findings[i].Severity))
+		rj := slices.Index(Severities, strings.ToLower(findings[j].Severity))
+		if ri != rj {
+			return ri < rj
+		}
+		return findings[i].CVE < findings[j].CVE
+	})
+
+	return findings
+}
+
+// MergeSarif combines the SARIF reports of every image, keyed by image name, into a single report
+// with the runs of all of them. Every run is tagged with the image it belongs to.
+func MergeSarif(reports map[string]SarifReport) SarifReport {
+	images := make([]string, 0, len(reports))
+	for image := range reports {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	merged := SarifReport{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []SarifRun{},
+	}
+	for _, image := range images {
+		for _, run := range reports[image].Runs {
+			run.AutomationDetails = &SarifAutomationDetails{ID: "skout/" + image}
+			merged.Runs = append(merged.Runs, run)
+		}
+	}
+
+	return merged
+}
+
+// WriteMergedSarif writes the merged SARIF report of every image into the given file.
+func WriteMergedSarif(filename string, reports map[string]SarifReport) error {
+	b, err := json.MarshalIndent(MergeSarif(reports), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, b, 0o644)
+}
@@ -0,0 +1,280 @@
+// Package scan discovers the container images running in a Kubernetes cluster and analyzes them with docker scout.
+// It holds the scan-and-aggregate logic of the skout CLI so that it can be embedded in other Go programs.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Result is the analysis result of an image.
+type Result struct {
+	Vulnerabilities Vulnerabilities
+	// Fixable is the part of Vulnerabilities that have a fixed version
+	Fixable Vulnerabilities
+	Report  SarifReport
+	// Err is the reason why the image could not be analyzed, if any
+	Err error
+	// SBOMErr is the reason why the SBOM of the image could not be generated, if requested
+	SBOMErr error
+	// Output is the raw output of Scanner.Command, if set
+	Output string
+	// Recommendations are the base image recommendations of docker scout for the image, if requested
+	Recommendations string
+	// RecommendationsErr is the reason why the recommendations could not be retrieved, if requested
+	RecommendationsErr error
+	// Duration is how long the analysis of the image took, including its SBOM and recommendations if requested
+	Duration time.Duration
+}
+
+// Scanner analyzes the images of the containers running in a Kubernetes cluster with docker scout.
+type Scanner struct {
+	Config Config
+	// Concurrency is the maximum number of images analyzed in parallel, 1 if not set
+	Concurrency int
+	// Cache stores the analysis results of images pinned to a digest between runs, if set
+	Cache *Cache
+	// NoCache is whether to analyze every image ignoring the cached results, which are still updated
+	NoCache bool
+	// SBOM is whether to also generate the SPDX SBOM of every image into the results directory, which is never cached
+	SBOM bool
+	// Command is the docker scout command run on every image instead of cves, with the arguments of Config, whose
+	// raw output is returned in Result.Output instead of the vulnerabilities
+	Command string
+	// Recommendations is whether to also get the base image recommendations of every image, which are never cached
+	Recommendations bool
+	// Workloads is whether Scan analyzes the pod templates of the workloads instead of the running pods
+	Workloads bool
+	// AllPhases is whether Scan analyzes the pods in any phase instead of only the running ones
+	AllPhases bool
+	// OnResult is called with the result of every image as soon as its analysis completes, if set.
+	// Calls are serialized, so it doesn't need to be safe for concurrent use.
+	OnResult func(image string, result Result)
+}
+
+// Scan lists the pods (or workloads) of the given namespace that match listOpts, analyzes their images and
+// returns them along with the vulnerabilities of every container whose severity is minSeverity or higher, all of
+// them if minSeverity is empty. Unless Config.Credentials is set, the images are pulled with the imagePullSecrets
+// of their pods.
+func (s Scanner) Scan(ctx context.Context, clientset kubernetes.Interface, namespace string, listOpts v1.ListOptions, minSeverity string) ([]Item, error) {
+	if minSeverity != "" && !slices.Contains(Severities, strings.ToLower(minSeverity)) {
+		return nil, fmt.Errorf("unsupported severity %q, must be one of: %s", minSeverity, strings.Join(Severities, ", "))
+	}
+
+	var (
+		items []Item
+		err   error
+	)
+	if s.Workloads {
+		items, err = ListWorkloads(ctx, clientset, namespace, listOpts)
+	} else {
+		items, err = ListPods(ctx, clientset, namespace, listOpts, s.AllPhases)
+	}
+	if err != nil {
+		return nil, err
+	}
+	DedupDigests(items)
+
+	if s.Config.Credentials == nil {
+		credentials, warnings := PullSecretCredentials(ctx, clientset, items)
+		for _, warning := range warnings {
+			slog.Warn(warning)
+		}
+		s.Config.Credentials = credentials
+	}
+
+	Apply(items, s.Analyze(ctx, Images(items)), minSeverity)
+
+	return items, nil
+}
+
+// Images returns the sorted references of the unique images analyzed for the containers of the given items.
+// The images are deduplicated across all the items, whatever their namespace, so that an image running in
+// several namespaces is analyzed once.
+func Images(items []Item) []string {
+	seen := make(map[string]bool)
+	var images []string
+	for _, item := range items {
+		for _, container := range item.Pod.Containers {
+			if !seen[container.ScanRef()] {
+				seen[container.ScanRef()] = true
+				images = append(images, container.ScanRef())
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// Analyze runs docker scout on the given images, up to Concurrency at the same time, and returns
+// the result of every image keyed by image name.
+func (s Scanner) Analyze(ctx context.Context, images []string) map[string]Result {
+	concurrency := s.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		// sem bounds the number of docker scout analyses running at the same time
+		sem = make(chan struct{}, concurrency)
+		// results holds the analysis result of every unique image, keyed by image name
+		results = make(map[string]Result, len(images))
+		// analyzed is the number of images whose analysis has completed, either successfully or not
+		analyzed int
+	)
+
+	for _, image := range images {
+		wg.Add(1)
+
+		image := image
+
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			result := s.analyze(ctx, image)
+			result.Duration = time.Since(start)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			analyzed++
+			attrs := []any{"image", image, "analyzed", analyzed, "images", len(images), "duration", result.Duration.Round(time.Millisecond).String()}
+			if result.Err != nil && !errors.Is(result.Err, ErrCanceled) {
+				// the failures are only reported once all the images are analyzed, so the progress just mentions it
+				attrs = append(attrs, "error", result.Err)
+			}
+			slog.Info("Analyzed image", attrs...)
+			if version := result.Report.ToolVersion(); result.Err == nil && version != "" {
+				slog.Debug("Analyzed image with docker scout", "image", image, "version", version)
+			}
+			results[image] = result
+			if s.OnResult != nil {
+				s.OnResult(image, result)
+			}
+		}()
+	}
+
+	slog.Debug("Waiting for all goroutines to complete")
+
+	wg.Wait()
+
+	return results
+}
+
+// analyze returns the vulnerabilities of the given image, or the output of Command, along with its SBOM and
+// recommendations if requested.
+func (s Scanner) analyze(ctx context.Context, image string) Result {
+	// the images waiting for their turn when the analysis is interrupted are not analyzed
+	if ctx.Err() != nil {
+		return Result{Err: ErrCanceled}
+	}
+
+	var result Result
+	if s.Command != "" && s.Command != "cves" {
+		result.Output, result.Err = RunCommand(ctx, image, s.Command, s.Config.Args, s.Config)
+	} else {
+		result = s.analyzeVulnerabilities(ctx, image)
+	}
+	if s.SBOM {
+		result.SBOMErr = GenerateSBOM(ctx, image, s.Config)
+	}
+	if s.Recommendations {
+		result.Recommendations, result.RecommendationsErr = Recommendations(ctx, image, s.Config)
+	}
+	return result
+}
+
+// analyzeVulnerabilities returns the cached result of the given image, if any, or runs docker scout on it otherwise.
+func (s Scanner) analyzeVulnerabilities(ctx context.Context, image string) Result {
+	if s.Cache != nil && !s.NoCache {
+		if entry, ok := s.Cache.Get(image); ok {
+			slog.Debug("Using cached analysis", "image", image, "scannedAt", entry.ScannedAt.Format(time.RFC3339))
+			if err := entry.Restore(s.Config.ResultsDir); err != nil {
+				slog.Warn("Failed to restore the SARIF report", "image", image, "error", err)
+			}
+			// the counts depend on Config.CountOccurrences, Config.IgnoreCVEs, Config.Baseline and Config.MinSeverity,
+			// which are not part of the cache key
+			report := s.prune(image, entry.Report)
+			vulns, fixable := report.Vulnerabilities(s.Config.CountOccurrences)
+			return Result{Vulnerabilities: vulns, Fixable: fixable, Report: report}
+		}
+	}
+
+	vulns, report, err := AnalyzeImage(ctx, image, s.Config)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	if s.Cache != nil {
+		if err := s.Cache.Put(image, vulns, report); err != nil {
+			slog.Warn("Failed to cache the analysis", "image", image, "error", err)
+		}
+	}
+
+	report = s.prune(image, report)
+	vulns, fixable := report.Vulnerabilities(s.Config.CountOccurrences)
+	return Result{Vulnerabilities: vulns, Fixable: fixable, Report: report}
+}
+
+// prune returns the given SARIF report of the image without the results of Config.IgnoreCVEs, those of the baseline
+// of the image and those below Config.MinSeverity.
+func (s Scanner) prune(image string, report SarifReport) SarifReport {
+	report = report.Without(s.Config.IgnoreCVEs)
+	if baseline := s.Config.Baseline[Repository(image)]; len(baseline) > 0 {
+		report = report.Without(baseline)
+	}
+	if s.Config.MinSeverity != "" {
+		report = report.AtLeast(s.Config.MinSeverity)
+	}
+	return report
+}
+
+// Apply fans out the results of every unique image to all the containers of the given items referencing it,
+// keeping the vulnerabilities whose severity is minSeverity or higher, all of them if empty, and returns their Totals.
+func Apply(items []Item, results map[string]Result, minSeverity string) (total, fixable Vulnerabilities) {
+	for i := range items {
+		for j := range items[i].Pod.Containers {
+			container := &items[i].Pod.Containers[j]
+
+			result := results[container.ScanRef()]
+			container.Duration = result.Duration.Seconds()
+			container.ScoutVersion = result.Report.ToolVersion()
+			if result.Err != nil {
+				container.Error = result.Err.Error()
+			} else {
+				container.Vulnerabilities = result.Vulnerabilities.AtLeast(minSeverity)
+				container.Fixable = result.Fixable.AtLeast(minSeverity)
+			}
+		}
+	}
+
+	return Totals(items)
+}
+
+// Totals returns the sum of the vulnerabilities of all the containers of the given items, along with the sum of
+// the fixable ones. An image referenced by several containers is counted once per container.
+func Totals(items []Item) (total, fixable Vulnerabilities) {
+	for _, item := range items {
+		for _, container := range item.Pod.Containers {
+			total.Add(container.Vulnerabilities)
+			fixable.Add(container.Fixable)
+		}
+	}
+	return total, fixable
+}
@@ -0,0 +1,371 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+)
+
+// DefaultImage is the docker/scout-cli image run when the docker scout CLI plugin is not installed.
+const DefaultImage = "docker/scout-cli:latest"
+
+// proxyEnv lists the proxy environment variables forwarded to the docker/scout-cli container when they are set,
+// so that it can reach the registries from behind a corporate proxy.
+var proxyEnv = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// dockerDesktopMinVersion is the first version of Docker Desktop that ships the "docker scout" CLI plugin.
+const dockerDesktopMinVersion = "4.17.0"
+
+// Config holds the settings used to invoke docker scout on every image.
+type Config struct {
+	// UseCLI is whether to use the docker scout CLI plugin instead of the docker/scout-cli image
+	UseCLI bool
+	// Image is the docker/scout-cli image run when UseCLI is not set, DefaultImage if empty
+	Image       string
+	HubUser     string
+	HubPassword string
+	// DockerConfigDir is the host directory with the docker config.json file holding the registries credentials
+	// mounted into the docker/scout-cli container, if any
+	DockerConfigDir string
+	// ResultsDir is the host directory where docker scout writes the SARIF reports
+	ResultsDir string
+	// RemoteEngine is whether the docker engine runs on another host, e.g. through DOCKER_HOST or a docker context,
+	// in which case the docker/scout-cli container can't mount host directories
+	RemoteEngine bool
+	// Args are the extra arguments forwarded to docker scout
+	Args []string
+	// Retries is the number of times docker scout is retried after a failure
+	Retries int
+	// RetryDelay is the delay before the first retry, doubled after every attempt
+	RetryDelay time.Duration
+	// Timeout is the maximum duration of the analysis of an image, including retries
+	Timeout time.Duration
+	// Credentials are the registry credentials used to pull the images, keyed by image name, e.g. those
+	// returned by PullSecretCredentials
+	Credentials map[string]RegistryCredential
+	// CountOccurrences is whether a CVE affecting several packages of an image is counted once per package
+	CountOccurrences bool
+	// Env are extra KEY=VALUE environment variables of docker scout, also forwarded to the docker/scout-cli
+	// container when UseCLI is not set
+	Env []string
+	// Mirrors are the prefixes of the mirrors the images are pulled from, keyed by registry, as used by Mirror
+	Mirrors map[string]string
+	// Baseline holds the IDs of the known vulnerabilities of every image repository, as returned by Repository,
+	// which are left out of the results so that only the new ones are reported
+	Baseline map[string][]string
+	// MinSeverity is the lowest severity of the results kept from the SARIF reports once parsed, all of them if empty
+	MinSeverity string
+	// IgnoreCVEs are the IDs of the vulnerabilities left out of the results, e.g. accepted risks, which are
+	// still written into the SARIF reports of the results directory
+	IgnoreCVEs []string
+}
+
+// ErrTimeout is returned when the analysis of an image does not complete before the configured timeout.
+var ErrTimeout = errors.New("analysis timed out")
+
+// ErrCanceled is returned when the analysis of an image is interrupted because its context is canceled.
+var ErrCanceled = errors.New("analysis canceled")
+
+// scoutWaitDelay is how long a docker command is waited for after being interrupted, before it is killed.
+const scoutWaitDelay = 10 * time.Second
+
+// ErrNotAnalyzed is returned when docker scout completes without analyzing the image, i.e. its SARIF report has
+// no runs, as it happens with some distroless and scratch-based images.
+var ErrNotAnalyzed = errors.New("not analyzed by docker scout, the SARIF report has no runs")
+
+// ErrInvalidReport is returned when the SARIF report written by docker scout can't be parsed, e.g. as it is truncated.
+// Like any other error, it only fails the analysis of the image the report belongs to.
+var ErrInvalidReport = errors.New("invalid SARIF report")
+
+// AnalyzeImage runs docker scout on the given image and returns the number of vulnerabilities by severity
+// along with the SARIF report generated by docker scout.
+func AnalyzeImage(ctx context.Context, image string, scout Config) (Vulnerabilities, SarifReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, scout.Timeout)
+	defer cancel()
+
+	reportFilename := SarifFilename(image)
+	args := append(slices.Clone(scout.Args), "--format", "sarif")
+	if err := runScout(ctx, scout, "cves", image, reportFilename, args, false); err != nil {
+		return Vulnerabilities{}, SarifReport{}, err
+	}
+
+	b, err := os.ReadFile(filepath.Join(scout.ResultsDir, reportFilename))
+	if err != nil {
+		return Vulnerabilities{}, SarifReport{}, fmt.Errorf("reading SARIF report: %w", err)
+	}
+	var report SarifReport
+
+	if err := json.Unmarshal(b, &report); err != nil {
+		return Vulnerabilities{}, SarifReport{}, fmt.Errorf("%w %s: %w", ErrInvalidReport, reportFilename, err)
+	}
+
+	if len(report.Runs) == 0 {
+		return Vulnerabilities{}, SarifReport{}, ErrNotAnalyzed
+	}
+
+	vulns, _ := report.Vulnerabilities(scout.CountOccurrences)
+	return vulns, report, nil
+}
+
+// GenerateSBOM runs docker scout on the given image to write its SPDX software bill of materials into the results
+// directory, in the file named by SBOMFilename.
+func GenerateSBOM(ctx context.Context, image string, scout Config) error {
+	ctx, cancel := context.WithTimeout(ctx, scout.Timeout)
+	defer cancel()
+
+	return runScout(ctx, scout, "sbom", image, SBOMFilename(image), []string{"--format", "spdx"}, false)
+}
+
+// Recommendations runs docker scout on the given image to get its base image recommendations, which are written
+// into the results directory, in the file named by OutputFilename, and returned.
+func Recommendations(ctx context.Context, image string, scout Config) (string, error) {
+	return RunCommand(ctx, image, "recommendations", nil, scout)
+}
+
+// RunCommand runs the given docker scout command on the image, with the given arguments, and returns its raw
+// output, which is also written into the results directory, in the file named by OutputFilename.
+func RunCommand(ctx context.Context, image, command string, args []string, scout Config) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, scout.Timeout)
+	defer cancel()
+
+	filename := OutputFilename(image, command)
+	if err := runScout(ctx, scout, command, image, filename, args, true); err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(filepath.Join(scout.ResultsDir, filename))
+	if err != nil {
+		return "", fmt.Errorf("reading docker scout %s output: %w", command, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// runScout runs the given docker scout command on the image, with the given arguments, retrying it on failure,
+// so that it writes its output into the given file of the results directory. Unless fromStdout is set, the
+// command must support the --output flag.
+func runScout(ctx context.Context, scout Config, command, image, filename string, commandArgs []string, fromStdout bool) error {
+	// the credentials of the pull secrets belong to the registry of the image, so they are never sent to its mirror
+	ref := Mirror(image, scout.Mirrors)
+	credential, hasCredential := scout.Credentials[image]
+	hasCredential = hasCredential && ref == image
+
+	// the containers of a remote engine can't write into the results directory, so the output is read from stdout
+	fromStdout = fromStdout || (!scout.UseCLI && scout.RemoteEngine)
+
+	var outDir string
+	// mountDir is the directory mounted into the docker/scout-cli container, if any, private to this run so that
+	// concurrent containers never write into the same directory
+	var mountDir string
+	var args []string
+	if scout.UseCLI {
+		args = []string{"scout", command}
+		outDir = scout.ResultsDir
+	} else {
+		// Run the containerized version of docker scout using the docker/scout-cli image
+		args = []string{
+			"run",
+			"--rm",
+			"-e", fmt.Sprintf("DOCKER_SCOUT_HUB_USER=%s", scout.HubUser),
+			"-e", fmt.Sprintf("DOCKER_SCOUT_HUB_PASSWORD=%s", scout.HubPassword),
+		}
+		if hasCredential {
+			// the values are taken from the environment of the docker command so they don't show up in its arguments
+			args = append(args, "-e", "DOCKER_SCOUT_REGISTRY_USER", "-e", "DOCKER_SCOUT_REGISTRY_PASSWORD")
+		}
+		for _, name := range proxyEnv {
+			if _, ok := os.LookupEnv(name); ok {
+				args = append(args, "-e", name)
+			}
+		}
+		for _, env := range scout.Env {
+			name, _, _ := strings.Cut(env, "=")
+			args = append(args, "-e", name)
+		}
+		if !fromStdout {
+			dir, err := filepath.Abs(scout.ResultsDir)
+			if err != nil {
+				return err
+			}
+			// created in the results directory so that the output is moved into it rather than copied
+			if mountDir, err = os.MkdirTemp(dir, ".scout-"); err != nil {
+				return err
+			}
+			defer os.RemoveAll(mountDir)
+			// writable by the user of the container, as the results directory is
+			if err := os.Chmod(mountDir, os.ModePerm); err != nil {
+				return err
+			}
+			args = append(args, "-v", fmt.Sprintf("%s:/tmp", mountDir))
+		}
+		if scout.DockerConfigDir != "" && !scout.RemoteEngine {
+			args = append(args,
+				"-e", "DOCKER_CONFIG=/docker-config",
+				"-v", fmt.Sprintf("%s:/docker-config:ro", scout.DockerConfigDir))
+		}
+		scoutImage := scout.Image
+		if scoutImage == "" {
+			scoutImage = DefaultImage
+		}
+		args = append(args, scoutImage, command)
+
+		outDir = "/tmp"
+	}
+
+	args = append(args, commandArgs...)
+	if !fromStdout {
+		args = append(args, "--output", filepath.Join(outDir, filename))
+	}
+	args = append(args, ref)
+
+	env := slices.Clone(scout.Env)
+	if hasCredential {
+		env = append(env,
+			"DOCKER_SCOUT_REGISTRY_USER="+credential.Username,
+			"DOCKER_SCOUT_REGISTRY_PASSWORD="+credential.Password)
+	}
+
+	delay := scout.RetryDelay
+	for attempt := 1; ; attempt++ {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "docker", args...)
+		// killing the docker CLI would leave the docker/scout-cli container running, whereas docker run forwards
+		// the interrupt signal to the container, which is then removed
+		cmd.Cancel = func() error {
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				return cmd.Process.Kill()
+			}
+			return nil
+		}
+		cmd.WaitDelay = scoutWaitDelay
+		cmd.Stderr = &stderr
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+		if fromStdout {
+			cmd.Stdout = &stdout
+		}
+		err := cmd.Run()
+		if err == nil {
+			if fromStdout {
+				if err := os.WriteFile(filepath.Join(scout.ResultsDir, filename), stdout.Bytes(), 0o644); err != nil {
+					return fmt.Errorf("writing docker scout %s output: %w", command, err)
+				}
+			}
+			if mountDir != "" {
+				if err := os.Rename(filepath.Join(mountDir, filename), filepath.Join(scout.ResultsDir, filename)); err != nil {
+					return fmt.Errorf("moving docker scout %s output: %w", command, err)
+				}
+			}
+			return nil
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s", ErrTimeout, scout.Timeout)
+		}
+		if ctx.Err() != nil {
+			return ErrCanceled
+		}
+
+		if attempt > scout.Retries {
+			return fmt.Errorf("running docker scout %s (%d attempts): %w", command, attempt, err)
+		}
+
+		slog.Debug("Attempt failed, retrying", "command", command, "image", image, "attempt", attempt, "delay", delay.String(), "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrCanceled
+			}
+			return fmt.Errorf("%w after %s", ErrTimeout, scout.Timeout)
+		}
+		delay *= 2
+	}
+}
+
+// ErrDockerNotRunning is returned when the docker daemon can't be reached.
+var ErrDockerNotRunning = errors.New("Docker does not appear to be running; start Docker and retry")
+
+// CanUseDockerScoutCLI returns whether the docker scout CLI plugin is installed, either because it is shipped
+// with Docker Desktop (4.17 or higher) or because it was installed manually, e.g. on a Linux Docker Engine host.
+func CanUseDockerScoutCLI() (bool, error) {
+	b, err := exec.Command("docker", "version").CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("%w (docker version: %s: %s)", ErrDockerNotRunning, err, strings.TrimSpace(string(b)))
+	}
+
+	if hasDockerScoutPlugin() {
+		slog.Info("The docker scout CLI plugin is installed")
+		return true, nil
+	}
+
+	var re = regexp.MustCompile(`(?m)Server: Docker Desktop (?P<version>.*) `)
+	for _, line := range strings.Split(string(b), "\n") {
+		if len(re.FindStringSubmatch(line)) == 2 {
+			detectedVersion, err := goversion.NewVersion(re.FindStringSubmatch(line)[1])
+			if err != nil {
+				return false, fmt.Errorf("parsing Docker Desktop version: %w", err)
+			}
+
+			minVersion, _ := goversion.NewVersion(dockerDesktopMinVersion)
+			if detectedVersion.GreaterThanOrEqual(minVersion) {
+				slog.Info("Docker Desktop version is greater or equal than the minimum version", "version", detectedVersion.String(), "minVersion", minVersion.String())
+				return true, nil
+			}
+
+		}
+	}
+
+	return false, nil
+}
+
+// IsRemoteDockerEngine returns whether the docker engine used by the docker CLI, as set by DOCKER_HOST or the
+// current docker context, runs on another host, i.e. its endpoint is neither a unix socket nor a named pipe.
+func IsRemoteDockerEngine() (bool, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		b, err := exec.Command("docker", "context", "inspect", "--format", "{{.Endpoints.docker.Host}}").Output()
+		if err != nil {
+			return false, fmt.Errorf("inspecting the current docker context: %w", err)
+		}
+		host = strings.TrimSpace(string(b))
+	}
+
+	return host != "" && !strings.HasPrefix(host, "unix://") && !strings.HasPrefix(host, "npipe://"), nil
+}
+
+// hasDockerScoutPlugin returns whether the docker scout CLI plugin is installed, by checking the exit code of "docker scout version".
+func hasDockerScoutPlugin() bool {
+	return exec.Command("docker", "scout", "version").Run() == nil
+}
+
+// SarifFilename returns the name of the SARIF report file of the given image.
+func SarifFilename(image string) string {
+	// replace the matched non-alphanumeric characters with the underscore character
+	return regexp.MustCompile(`[^a-zA-Z-0-9]+`).ReplaceAllString(image, "_") + ".sarif.json"
+}
+
+// OutputFilename returns the name of the file holding the raw output of the given docker scout command for the image.
+func OutputFilename(image, command string) string {
+	return strings.TrimSuffix(SarifFilename(image), ".sarif.json") + "." + command + ".txt"
+}
+
+// SBOMFilename returns the name of the SPDX SBOM file of the given image.
+func SBOMFilename(image string) string {
+	return strings.TrimSuffix(SarifFilename(image), ".sarif.json") + ".spdx.json"
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/felipecruz91/skout/scan"
+)
+
+// defaultWeights are the weights of the severities in the risk score unless set with --weights.
+const defaultWeights = "critical=10,high=5,medium=2,low=1"
+
+// Weights holds the weight of every severity in the risk score, a single number summing up the vulnerabilities.
+type Weights struct {
+	Critical int
+	High     int
+	Medium   int
+	Low      int
+}
+
+// parseWeights parses weights formatted as comma-separated severity=weight pairs, e.g. "critical=10,high=5".
+// The severities not listed weigh 0.
+func parseWeights(s string) (Weights, error) {
+	var w Weights
+	for _, pair := range strings.Split(s, ",") {
+		severity, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return w, fmt.Errorf("invalid weight %q, must be severity=weight", pair)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return w, fmt.Errorf("invalid weight %q, must be a non-negative integer", value)
+		}
+		switch strings.ToLower(severity) {
+		case "critical":
+			w.Critical = weight
+		case "high":
+			w.High = weight
+		case "medium":
+			w.Medium = weight
+		case "low":
+			w.Low = weight
+		default:
+			return w, fmt.Errorf("unsupported severity %q, must be one of: %s", severity, strings.Join(scan.Severities, ", "))
+		}
+	}
+	return w, nil
+}
+
+// Score returns the risk score of the given vulnerabilities, the sum of their number weighted by severity.
+func (w Weights) Score(v scan.Vulnerabilities) int {
+	return w.Critical*v.Critical + w.High*v.High + w.Medium*v.Medium + w.Low*v.Low
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// slackTimeout is the maximum duration of the request posting the summary to Slack.
+const slackTimeout = 10 * time.Second
+
+// slackSummary returns the Slack message summarizing the report: the total number of vulnerabilities by severity
+// and the images whose vulnerabilities exceed the thresholds, for the given cluster context and namespace.
+func slackSummary(report Report, clusterContext, namespace string) string {
+	if namespace == "" {
+		namespace = "all namespaces"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*skout scan of %s*", namespace)
+	if clusterContext != "" {
+		fmt.Fprintf(&b, " in context `%s`", clusterContext)
+	}
+	fmt.Fprintf(&b, "\nCritical: %d, High: %d, Medium: %d, Low: %d (total %d)",
+		report.Total.Critical, report.Total.High, report.Total.Medium, report.Total.Low, report.Total.Total())
+
+	// images holds the breaches of every image exceeding the thresholds, keyed by image name
+	images := make(map[string][]string)
+	failed := 0
+	for _, item := range report.Items {
+		for _, container := range item.Pod.Containers {
+			if container.Error != "" {
+				failed++
+				continue
+			}
+			if breaches := report.thresholds.Breaches(container.Vulnerabilities); len(breaches) > 0 {
+				images[container.Image] = breaches
+			}
+		}
+	}
+
+	if len(images) > 0 {
+		names := make([]string, 0, len(images))
+		for name := range images {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		b.WriteString("\nImages exceeding the thresholds:")
+		for _, name := range names {
+			fmt.Fprintf(&b, "\n• `%s`: %s", name, strings.Join(images[name], ", "))
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(&b, "\n%d containers could not be analyzed", failed)
+	}
+
+	return b.String()
+}
+
+// postSlackMessage posts the given message to a Slack incoming webhook.
+func postSlackMessage(ctx context.Context, webhookURL, message string) error {
+	ctx, cancel := context.WithTimeout(ctx, slackTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]string{"text": message})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	return nil
+}
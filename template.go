@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// parseTemplateFile parses the Go text/template of the given file, used to render the report with --report-format template.
+func parseTemplateFile(filename string) (*template.Template, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(filepath.Base(filename)).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// writeTemplate renders the report into w with the template set with --template-file, executed against the Report.
+func writeTemplate(w io.Writer, report Report) error {
+	if report.template == nil {
+		return fmt.Errorf("report format %s requires a template", reportFormatTemplate)
+	}
+	return report.template.Execute(w, report)
+}
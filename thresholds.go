@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/felipecruz91/skout/scan"
+)
+
+// unlimited is the threshold value that never fails the analysis.
+const unlimited = -1
+
+// Thresholds holds the maximum number of vulnerabilities allowed per severity, and in total, and the maximum risk
+// score before the analysis is considered failed. A value of unlimited disables the check for that severity.
+type Thresholds struct {
+	Critical int
+	High     int
+	Medium   int
+	Low      int
+	Total    int
+	Score    int
+	// Weights are the weights of the severities in the risk score compared to Score
+	Weights Weights
+}
+
+// newThresholds returns thresholds that never fail the analysis.
+func newThresholds() Thresholds {
+	return Thresholds{Critical: unlimited, High: unlimited, Medium: unlimited, Low: unlimited, Total: unlimited, Score: unlimited}
+}
+
+// failOn returns the thresholds that fail the analysis when at least one vulnerability of the given
+// severity or higher is found.
+func failOn(severity string) (Thresholds, error) {
+	t := newThresholds()
+	switch strings.ToLower(severity) {
+	case "low":
+		t.Low = 0
+		fallthrough
+	case "medium":
+		t.Medium = 0
+		fallthrough
+	case "high":
+		t.High = 0
+		fallthrough
+	case "critical":
+		t.Critical = 0
+	default:
+		return t, fmt.Errorf("unsupported severity %q, must be one of: %s", severity, strings.Join(scan.Severities, ", "))
+	}
+	return t, nil
+}
+
+// Breaches returns a description of every threshold exceeded by the given vulnerabilities.
+func (t Thresholds) Breaches(v scan.Vulnerabilities) []string {
+	var breaches []string
+	check := func(severity string, count, max int) {
+		if max != unlimited && count > max {
+			breaches = append(breaches, fmt.Sprintf("%d %s vulnerabilities found, maximum allowed is %d", count, severity, max))
+		}
+	}
+	check("critical", v.Critical, t.Critical)
+	check("high", v.High, t.High)
+	check("medium", v.Medium, t.Medium)
+	check("low", v.Low, t.Low)
+	if t.Total != unlimited && v.Total() > t.Total {
+		breaches = append(breaches, fmt.Sprintf("%d vulnerabilities found in total, maximum allowed is %d", v.Total(), t.Total))
+	}
+	if score := t.Weights.Score(v); t.Score != unlimited && score > t.Score {
+		breaches = append(breaches, fmt.Sprintf("risk score is %d, maximum allowed is %d", score, t.Score))
+	}
+	return breaches
+}
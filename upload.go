@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// uploadReportFilename is the name of the JSON report uploaded along with the files of the results directory.
+const uploadReportFilename = "skout-report.json"
+
+// uploadTimeout is the maximum duration of the upload of all the results.
+const uploadTimeout = 5 * time.Minute
+
+// uploader stores files in an object store.
+type uploader interface {
+	// Upload stores the content of r in the object with the given key, relative to the prefix of the uploader.
+	Upload(ctx context.Context, key string, r io.Reader) error
+}
+
+// parseUploadURL parses the object store URL of the --upload flag, e.g. s3://bucket/prefix, into its bucket and prefix.
+func parseUploadURL(rawURL string) (bucket, prefix string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid upload URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("unsupported upload URL %q, must be s3://BUCKET[/PREFIX]", rawURL)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// newUploader returns the uploader of the object store of the given URL, configured from the environment.
+func newUploader(ctx context.Context, rawURL string) (uploader, error) {
+	bucket, prefix, err := parseUploadURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// uses the credentials and region of the AWS environment variables, shared config files or instance role
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS configuration: %w", err)
+	}
+
+	return s3Uploader{client: s3.NewFromConfig(cfg), bucket: bucket, prefix: prefix}, nil
+}
+
+// s3Uploader stores files in an S3 bucket, under the given prefix.
+type s3Uploader struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (u s3Uploader) Upload(ctx context.Context, key string, r io.Reader) error {
+	_, err := u.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(path.Join(u.prefix, key)),
+		Body:   r,
+	})
+	return err
+}
+
+// uploadResults uploads the SARIF reports and SBOMs of the results directory, along with the report as JSON,
+// to the object store of the given URL. It returns the number of uploaded files.
+func uploadResults(ctx context.Context, rawURL, resultsDir string, report Report) (int, error) {
+	u, err := newUploader(ctx, rawURL)
+	if err != nil {
+		return 0, err
+	}
+
+	var b bytes.Buffer
+	if err := writeJSON(&b, report); err != nil {
+		return 0, err
+	}
+	if err := u.Upload(ctx, uploadReportFilename, bytes.NewReader(b.Bytes())); err != nil {
+		return 0, fmt.Errorf("uploading %s: %w", uploadReportFilename, err)
+	}
+	uploaded := 1
+
+	entries, err := os.ReadDir(resultsDir)
+	if err != nil {
+		return uploaded, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		if err := uploadFile(ctx, u, filepath.Join(resultsDir, entry.Name())); err != nil {
+			return uploaded, fmt.Errorf("uploading %s: %w", entry.Name(), err)
+		}
+		uploaded++
+	}
+
+	return uploaded, nil
+}
+
+// uploadFile uploads the given file with its base name as key.
+func uploadFile(ctx context.Context, u uploader, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return u.Upload(ctx, filepath.Base(filename), f)
+}